@@ -0,0 +1,467 @@
+package vecto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// CassetteMode selects how a Cassette handles requests.
+type CassetteMode int
+
+const (
+	// CassetteReplay serves every request from the cassette file without
+	// sending it. A request with no matching interaction fails with a
+	// *CassetteMismatchError.
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord sends every request and records the interaction. The
+	// cassette file is replaced on Save or Close.
+	CassetteRecord
+	// CassettePassthrough sends every request without recording it.
+	CassettePassthrough
+)
+
+func (m CassetteMode) String() string {
+	switch m {
+	case CassetteReplay:
+		return "replay"
+	case CassetteRecord:
+		return "record"
+	case CassettePassthrough:
+		return "passthrough"
+	default:
+		return "unknown"
+	}
+}
+
+// CassetteMatcher reports whether a live request matches a recorded one.
+// Both requests have their scrubbed headers already replaced.
+type CassetteMatcher func(live, recorded *CassetteRequest) bool
+
+// CassetteConfig configures a Cassette.
+type CassetteConfig struct {
+	// Path is the cassette file, in JSON.
+	Path string
+
+	// Mode selects whether requests are replayed, recorded or sent as-is.
+	// Default: CassetteReplay
+	Mode CassetteMode
+
+	// Matchers select the recorded interaction replayed for a request.
+	// An interaction matches when every matcher returns true.
+	// Default: MatchMethod, MatchURL
+	Matchers []CassetteMatcher
+
+	// ScrubHeaders are header names whose values are replaced with
+	// DefaultRedactionReplacement before they are recorded, in addition to
+	// DefaultSensitiveHeaders. Names are case-insensitive.
+	// Default: nil
+	ScrubHeaders []string
+
+	// AllowRepeats lets an interaction be replayed more than once. Otherwise
+	// each interaction is replayed once, in recorded order, so a retried
+	// request gets its recorded failures before its success.
+	// Default: false
+	AllowRepeats bool
+}
+
+// CassetteRequest is a recorded request.
+type CassetteRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// CassetteResponse is a recorded response. Bodies that are not UTF-8 are
+// stored base64 encoded, with BodyEncoding set to "base64".
+type CassetteResponse struct {
+	StatusCode   int         `json:"statusCode"`
+	Proto        string      `json:"proto,omitempty"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"bodyEncoding,omitempty"`
+}
+
+// CassetteInteraction is a recorded request and its response.
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteMismatchError is returned in replay mode for a request that
+// matches no interaction of the cassette.
+type CassetteMismatchError struct {
+	Method string
+	URL    string
+	Path   string
+}
+
+func (e *CassetteMismatchError) Error() string {
+	return fmt.Sprintf("no interaction in cassette %s matches %s %s", e.Path, e.Method, e.URL)
+}
+
+type cassetteFile struct {
+	Interactions []CassetteInteraction `json:"interactions"`
+}
+
+// Cassette records the interactions of a client to a file and replays them,
+// so tests calling real APIs run deterministically without the network. It
+// is installed as TransportMiddleware, so request middleware, retries and
+// the circuit breaker still run as usual.
+//
+// Example:
+//
+//	mode := vecto.CassetteReplay
+//	if os.Getenv("RECORD") != "" {
+//	    mode = vecto.CassetteRecord
+//	}
+//	cassette, err := vecto.NewCassette(vecto.CassetteConfig{
+//	    Path: "testdata/users.json",
+//	    Mode: mode,
+//	})
+//	defer cassette.Close()
+//	client, _ := vecto.New(vecto.Config{
+//	    TransportMiddleware: []vecto.RoundTripperMiddleware{cassette.Middleware()},
+//	})
+type Cassette struct {
+	config       CassetteConfig
+	scrubber     *redactor
+	mu           sync.Mutex
+	interactions []CassetteInteraction
+	replayed     []bool
+
+	// writeMu serializes the writes to the cassette file.
+	writeMu sync.Mutex
+}
+
+// NewCassette returns a Cassette with the given config. In replay mode the
+// cassette file is loaded and must exist.
+func NewCassette(config CassetteConfig) (*Cassette, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("cassette path is required")
+	}
+
+	if config.Mode < CassetteReplay || config.Mode > CassettePassthrough {
+		return nil, fmt.Errorf("invalid cassette mode: %d", config.Mode)
+	}
+
+	if len(config.Matchers) == 0 {
+		config.Matchers = []CassetteMatcher{MatchMethod, MatchURL}
+	}
+
+	c := &Cassette{
+		config:   config,
+		scrubber: newRedactor(&RedactionConfig{Headers: config.ScrubHeaders}),
+	}
+
+	if config.Mode != CassetteReplay {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cassette: %w", err)
+	}
+
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to load cassette %s: %w", config.Path, err)
+	}
+
+	c.interactions = file.Interactions
+	c.replayed = make([]bool, len(file.Interactions))
+
+	return c, nil
+}
+
+// Mode returns the mode of the cassette.
+func (c *Cassette) Mode() CassetteMode {
+	return c.config.Mode
+}
+
+// Interactions returns a copy of the interactions loaded or recorded.
+func (c *Cassette) Interactions() []CassetteInteraction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CassetteInteraction(nil), c.interactions...)
+}
+
+// Save writes the interactions to the cassette file, replacing it.
+// Requests do not write the file, so in record mode Save or Close must be
+// called once the interactions are recorded.
+func (c *Cassette) Save() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	interactions := c.Interactions()
+	if interactions == nil {
+		interactions = []CassetteInteraction{}
+	}
+
+	data, err := json.MarshalIndent(cassetteFile{Interactions: interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(c.config.Path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to save cassette: %w", err)
+	}
+
+	return nil
+}
+
+// Close saves the recorded interactions in record mode. It does nothing in
+// the other modes.
+func (c *Cassette) Close() error {
+	if c.config.Mode != CassetteRecord {
+		return nil
+	}
+	return c.Save()
+}
+
+// Middleware returns the TransportMiddleware that records or replays the
+// requests of a client.
+func (c *Cassette) Middleware() RoundTripperMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			switch c.config.Mode {
+			case CassetteReplay:
+				return c.replay(req)
+			case CassetteRecord:
+				return c.record(next, req)
+			default:
+				return next.RoundTrip(req)
+			}
+		})
+	}
+}
+
+func (c *Cassette) replay(req *http.Request) (*http.Response, error) {
+	live, _, err := c.liveRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	interaction, ok := c.matchUnsafe(live)
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, &CassetteMismatchError{
+			Method: live.Method,
+			URL:    live.URL,
+			Path:   c.config.Path,
+		}
+	}
+
+	return interaction.Response.httpResponse(req)
+}
+
+// matchUnsafe returns the first interaction matching live that was not
+// replayed yet or, with AllowRepeats, the last matching one.
+func (c *Cassette) matchUnsafe(live *CassetteRequest) (CassetteInteraction, bool) {
+	last := -1
+	for i := range c.interactions {
+		if !c.matches(live, &c.interactions[i].Request) {
+			continue
+		}
+		if !c.replayed[i] {
+			c.replayed[i] = true
+			return c.interactions[i], true
+		}
+		last = i
+	}
+
+	if c.config.AllowRepeats && last >= 0 {
+		return c.interactions[last], true
+	}
+
+	return CassetteInteraction{}, false
+}
+
+func (c *Cassette) matches(live, recorded *CassetteRequest) bool {
+	for _, matcher := range c.config.Matchers {
+		if matcher != nil && !matcher(live, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Cassette) record(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	live, body, err := c.liveRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	res, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	interaction := CassetteInteraction{
+		Request: *live,
+		Response: CassetteResponse{
+			StatusCode: res.StatusCode,
+			Proto:      res.Proto,
+			Headers:    c.scrub(res.Header),
+		},
+	}
+	interaction.Response.setBody(resBody)
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, interaction)
+	c.mu.Unlock()
+
+	return res, nil
+}
+
+// liveRequest returns req as a scrubbed CassetteRequest and its body, which
+// is consumed from req.
+func (c *Cassette) liveRequest(req *http.Request) (*CassetteRequest, []byte, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &CassetteRequest{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Headers: c.scrub(req.Header),
+		Body:    string(body),
+	}, body, nil
+}
+
+func (c *Cassette) scrub(headers http.Header) http.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+	return http.Header(c.scrubber.headerValues(headers))
+}
+
+func (r *CassetteResponse) setBody(body []byte) {
+	if utf8.Valid(body) {
+		r.Body = string(body)
+		return
+	}
+	r.Body = base64.StdEncoding.EncodeToString(body)
+	r.BodyEncoding = "base64"
+}
+
+func (r *CassetteResponse) httpResponse(req *http.Request) (*http.Response, error) {
+	body := []byte(r.Body)
+	if r.BodyEncoding == "base64" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(r.Body); err != nil {
+			return nil, fmt.Errorf("invalid cassette response body: %w", err)
+		}
+	}
+
+	proto := r.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	major, minor, ok := http.ParseHTTPVersion(proto)
+	if !ok {
+		major, minor = 1, 1
+	}
+
+	headers := r.Headers.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        headers,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// MatchMethod matches requests with the same method.
+func MatchMethod(live, recorded *CassetteRequest) bool {
+	return strings.EqualFold(live.Method, recorded.Method)
+}
+
+// MatchURL matches requests with the same URL. Query parameters may be in
+// any order.
+func MatchURL(live, recorded *CassetteRequest) bool {
+	if live.URL == recorded.URL {
+		return true
+	}
+
+	a, err := url.Parse(live.URL)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+
+	return a.Scheme == b.Scheme &&
+		a.Host == b.Host &&
+		a.Path == b.Path &&
+		reflect.DeepEqual(a.Query(), b.Query())
+}
+
+// MatchBody matches requests with the same body. JSON bodies match when
+// they hold the same values, whatever their formatting and key order.
+func MatchBody(live, recorded *CassetteRequest) bool {
+	if live.Body == recorded.Body {
+		return true
+	}
+
+	var a, b interface{}
+	if json.Unmarshal([]byte(live.Body), &a) != nil || json.Unmarshal([]byte(recorded.Body), &b) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// MatchHeaders returns a matcher for requests with the same values of the
+// given headers.
+func MatchHeaders(names ...string) CassetteMatcher {
+	return func(live, recorded *CassetteRequest) bool {
+		for _, name := range names {
+			if !reflect.DeepEqual(live.Headers.Values(name), recorded.Headers.Values(name)) {
+				return false
+			}
+		}
+		return true
+	}
+}
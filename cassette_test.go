@@ -0,0 +1,171 @@
+package vecto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCassetteClient(t *testing.T, baseURL string, cassette *Cassette, config Config) *Vecto {
+	t.Helper()
+
+	config.BaseURL = baseURL
+	config.TransportMiddleware = append(config.TransportMiddleware, cassette.Middleware())
+	v, err := New(config)
+	require.NoError(t, err)
+
+	return v
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Write([]byte(`{"echo":` + string(body) + `}`))
+	}))
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	retry := &RetryConfig{MaxAttempts: 2, WaitTime: time.Millisecond}
+	options := &RequestOptions{
+		Headers: map[string]string{"Authorization": "Bearer secret", "X-Tenant": "acme"},
+		Params:  map[string]any{"a": 1, "b": 2},
+		Data:    map[string]string{"name": "John"},
+	}
+
+	recorder, err := NewCassette(CassetteConfig{Path: path, Mode: CassetteRecord, ScrubHeaders: []string{"X-Tenant"}})
+	require.NoError(t, err)
+	v := newCassetteClient(t, srv.URL, recorder, Config{Retry: retry})
+
+	recorded, err := v.Post(context.Background(), "/users", options)
+	require.NoError(t, err)
+	srv.Close()
+
+	interactions := recorder.Interactions()
+	require.Len(t, interactions, 2, "both attempts are recorded")
+	assert.Equal(t, http.StatusServiceUnavailable, interactions[0].Response.StatusCode)
+	assert.Equal(t, "[REDACTED]", interactions[1].Request.Headers.Get("Authorization"))
+	assert.Equal(t, "[REDACTED]", interactions[1].Request.Headers.Get("X-Tenant"))
+	assert.Equal(t, "[REDACTED]", interactions[1].Response.Headers.Get("Set-Cookie"))
+	assert.JSONEq(t, `{"name":"John"}`, interactions[1].Request.Body)
+
+	assert.NoFileExists(t, path, "requests do not write the cassette")
+	require.NoError(t, recorder.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+
+	var middlewareCalls int
+	replayer, err := NewCassette(CassetteConfig{Path: path, Matchers: []CassetteMatcher{MatchMethod, MatchURL, MatchBody}})
+	require.NoError(t, err)
+	v = newCassetteClient(t, srv.URL, replayer, Config{Retry: retry})
+	v.UseRequest(func(ctx context.Context, req *Request) (*Request, error) {
+		middlewareCalls++
+		return req, nil
+	})
+
+	replayed, err := v.Post(context.Background(), "/users", options)
+	require.NoError(t, err)
+	assert.Equal(t, 1, middlewareCalls)
+	assert.Equal(t, recorded.StatusCode, replayed.StatusCode)
+	assert.Equal(t, string(recorded.Data), string(replayed.Data))
+	assert.Equal(t, "application/json", replayed.Header("Content-Type"))
+
+	_, err = v.Post(context.Background(), "/users", options)
+	var mismatch *CassetteMismatchError
+	require.True(t, errors.As(err, &mismatch), "interactions are replayed once: %v", err)
+	assert.Equal(t, http.MethodPost, mismatch.Method)
+}
+
+func TestCassetteReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"interactions": [
+		{"request": {"method": "GET", "url": "http://api.test/items?b=2&a=1", "headers": {"X-Version": ["2"]}},
+		 "response": {"statusCode": 200, "body": "v2"}},
+		{"request": {"method": "GET", "url": "http://api.test/items?a=1&b=2", "headers": {"X-Version": ["1"]}},
+		 "response": {"statusCode": 200, "body": "v1"}},
+		{"request": {"method": "GET", "url": "http://api.test/binary"},
+		 "response": {"statusCode": 200, "body": "//79", "bodyEncoding": "base64"}}
+	]}`), 0o600))
+
+	cassette, err := NewCassette(CassetteConfig{
+		Path:         path,
+		Matchers:     []CassetteMatcher{MatchMethod, MatchURL, MatchHeaders("X-Version")},
+		AllowRepeats: true,
+	})
+	require.NoError(t, err)
+	v := newCassetteClient(t, "http://api.test", cassette, Config{})
+
+	for i := 0; i < 2; i++ {
+		res, err := v.Get(context.Background(), "/items?a=1&b=2", &RequestOptions{Headers: map[string]string{"X-Version": "1"}})
+		require.NoError(t, err)
+		assert.Equal(t, "v1", string(res.Data))
+	}
+
+	res, err := v.Get(context.Background(), "/binary", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0xfe, 0xfd}, res.Data)
+
+	_, err = v.Get(context.Background(), "/missing", nil)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "no interaction in cassette"), err.Error())
+}
+
+func TestCassettePassthrough(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("live"))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cassette, err := NewCassette(CassetteConfig{Path: path, Mode: CassettePassthrough})
+	require.NoError(t, err)
+	v := newCassetteClient(t, srv.URL, cassette, Config{})
+
+	res, err := v.Get(context.Background(), "/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "live", string(res.Data))
+	assert.Empty(t, cassette.Interactions())
+	require.NoError(t, cassette.Close())
+	assert.NoFileExists(t, path)
+}
+
+func TestNewCassetteErrors(t *testing.T) {
+	_, err := NewCassette(CassetteConfig{})
+	assert.Error(t, err)
+
+	_, err = NewCassette(CassetteConfig{Path: filepath.Join(t.TempDir(), "missing.json")})
+	assert.Error(t, err)
+
+	_, err = NewCassette(CassetteConfig{Path: "cassette.json", Mode: CassettePassthrough + 1})
+	assert.Error(t, err)
+
+	cassette, err := NewCassette(CassetteConfig{Path: filepath.Join(t.TempDir(), "missing", "cassette.json"), Mode: CassetteRecord})
+	require.NoError(t, err)
+	assert.ErrorContains(t, cassette.Close(), "failed to save cassette")
+}
+
+func TestCassetteMatchers(t *testing.T) {
+	assert.True(t, MatchMethod(&CassetteRequest{Method: "get"}, &CassetteRequest{Method: "GET"}))
+	assert.True(t, MatchURL(&CassetteRequest{URL: "http://a/p?x=1&y=2"}, &CassetteRequest{URL: "http://a/p?y=2&x=1"}))
+	assert.False(t, MatchURL(&CassetteRequest{URL: "http://a/p?x=1"}, &CassetteRequest{URL: "http://a/p?x=2"}))
+	assert.True(t, MatchBody(&CassetteRequest{Body: `{"a":1,"b":2}`}, &CassetteRequest{Body: `{ "b": 2, "a": 1 }`}))
+	assert.False(t, MatchBody(&CassetteRequest{Body: "a=1"}, &CassetteRequest{Body: "a=2"}))
+}
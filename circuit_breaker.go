@@ -315,10 +315,10 @@ func (cb *CircuitBreaker) logStateChange(from, to CircuitBreakerState) {
 
 	ctx := context.Background()
 	cb.config.Logger.Info(ctx, "circuit breaker state changed", map[string]interface{}{
-		"key":      cb.key,
-		"from":     from.String(),
-		"to":       to.String(),
-		"failures": len(cb.failures),
+		"breaker_key": cb.key,
+		"from":        from.String(),
+		"to":          to.String(),
+		"failures":    len(cb.failures),
 	})
 }
 
@@ -383,6 +383,9 @@ type CircuitBreakerManager struct {
 	breakers      map[string]*CircuitBreaker
 	defaultConfig CircuitBreakerConfig
 	logger        Logger
+
+	// onCreate is called with the key of each new breaker.
+	onCreate func(key string)
 }
 
 // NewCircuitBreakerManager creates a new circuit breaker manager.
@@ -423,6 +426,10 @@ func (m *CircuitBreakerManager) GetOrCreate(key string, config *CircuitBreakerCo
 	breaker := NewCircuitBreaker(key, cbConfig)
 	m.breakers[key] = breaker
 
+	if m.onCreate != nil {
+		m.onCreate(key)
+	}
+
 	return breaker
 }
 
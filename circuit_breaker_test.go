@@ -289,3 +289,25 @@ func TestCircuitBreaker_ConcurrentAccess(t *testing.T) {
 	assert.Equal(t, StateClosed, stats.State)
 }
 
+func TestCircuitBreakerKeyForRequest(t *testing.T) {
+	cbConfig := DefaultCircuitBreakerConfig()
+	v, err := New(Config{BaseURL: "https://api.example.com", CircuitBreaker: &cbConfig})
+	require.NoError(t, err)
+
+	req, err := v.newRequest("/users", "GET", nil)
+	require.NoError(t, err)
+
+	done := make(chan string, 1)
+	go func() {
+		done <- v.requestHandler.getOrSetCircuitBreakerKey(req)
+	}()
+
+	select {
+	case key := <-done:
+		assert.Equal(t, "https://api.example.com", key)
+	case <-time.After(time.Second):
+		t.Fatal("computing the circuit breaker key deadlocked")
+	}
+
+	assert.Equal(t, "https://api.example.com", v.requestHandler.getOrSetCircuitBreakerKey(req))
+}
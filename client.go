@@ -13,6 +13,7 @@ type DefaultClient struct {
 	client              http.Client
 	maxResponseBodySize int64
 	enableTrace         bool
+	pool                *poolTracker
 }
 
 func (c *DefaultClient) Do(ctx context.Context, req *Request) (res *Response, err error) {
@@ -22,20 +23,27 @@ func (c *DefaultClient) Do(ctx context.Context, req *Request) (res *Response, er
 	if c.enableTrace {
 		tc = &traceContext{}
 		trace := createClientTrace(tc)
-		ctx = httptrace.WithClientTrace(ctx, trace)
+		ctx = httptrace.WithClientTrace(withTraceContext(ctx, tc), trace)
 		tc.requestStart = time.Now()
 	}
 
+	if c.pool != nil {
+		var release func()
+		ctx, release = c.pool.trackRequest(ctx)
+		defer release()
+	}
+
 	httpReq, err := req.toHTTPRequest(ctx)
 	if err != nil {
 		return res, err
 	}
 
+	recordRequestSize := countRequestBody(ctx, httpReq)
+	defer recordRequestSize()
+
 	httpRes, err := c.client.Do(httpReq)
 	if err != nil {
-		if tc != nil {
-			tc.requestEnd = time.Now()
-		}
+		finishTrace(ctx, tc, nil)
 		return res, err
 	}
 
@@ -49,16 +57,11 @@ func (c *DefaultClient) Do(ctx context.Context, req *Request) (res *Response, er
 	limitedReader := io.LimitReader(httpRes.Body, maxSize)
 	resBody, err := io.ReadAll(limitedReader)
 	if err != nil {
-		if tc != nil {
-			tc.requestEnd = time.Now()
-		}
+		finishTrace(ctx, tc, httpRes)
 		return res, err
 	}
 
-	if tc != nil {
-		tc.requestEnd = time.Now()
-		traceInfo = computeTraceInfo(tc)
-	}
+	traceInfo = finishTrace(ctx, tc, httpRes)
 
 	if int64(len(resBody)) >= maxSize {
 		var oneByte [1]byte
@@ -75,6 +78,7 @@ func (c *DefaultClient) Do(ctx context.Context, req *Request) (res *Response, er
 		RawResponse: httpRes,
 		request:     req,
 		TraceInfo:   traceInfo,
+		Protocol:    httpRes.Proto,
 	}
 
 	return res, nil
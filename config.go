@@ -23,24 +23,56 @@ func validateConfig(config Config) error {
 		}
 	}
 
+	if config.LogLevel < LogLevelDebug || config.LogLevel > LogLevelError {
+		return fmt.Errorf("invalid log level: %d", config.LogLevel)
+	}
+
 	if err := validateHeaders(config.Headers); err != nil {
 		return fmt.Errorf("invalid headers: %w", err)
 	}
 
+	if err := validateTLSConfig(config.TLS); err != nil {
+		return fmt.Errorf("invalid TLS config: %w", err)
+	}
+
+	if err := validateProxyConfig(config.Proxy); err != nil {
+		return fmt.Errorf("invalid proxy config: %w", err)
+	}
+
+	if err := validateDialOverrides(config.DialOverrides); err != nil {
+		return fmt.Errorf("invalid dial overrides: %w", err)
+	}
+
+	if err := validateDNSConfig(config.DNS); err != nil {
+		return fmt.Errorf("invalid DNS config: %w", err)
+	}
+
+	if err := validateTransportConfig(config.Transport); err != nil {
+		return fmt.Errorf("invalid transport config: %w", err)
+	}
+
+	if err := validateHTTPTransport(config); err != nil {
+		return fmt.Errorf("invalid HTTP transport: %w", err)
+	}
+
+	if err := validateRedactionConfig(config.Redaction); err != nil {
+		return fmt.Errorf("invalid redaction config: %w", err)
+	}
+
 	return nil
 }
 
 func mergeConfig(provided, defaults Config) Config {
 	result := Config{
-		BaseURL:                defaults.BaseURL,
-		Timeout:                defaults.Timeout,
-		Headers:                cloneHeaders(defaults.Headers),
-		Certificates:           cloneCertificates(defaults.Certificates),
-		HTTPTransport:          defaults.HTTPTransport,
-		Adapter:                defaults.Adapter,
-		RequestTransform:       defaults.RequestTransform,
-		ValidateStatus:         defaults.ValidateStatus,
-		InsecureSkipVerify:     defaults.InsecureSkipVerify,
+		BaseURL:             defaults.BaseURL,
+		Timeout:             defaults.Timeout,
+		Headers:             cloneHeaders(defaults.Headers),
+		Certificates:        cloneCertificates(defaults.Certificates),
+		HTTPTransport:       defaults.HTTPTransport,
+		Adapter:             defaults.Adapter,
+		RequestTransform:    defaults.RequestTransform,
+		ValidateStatus:      defaults.ValidateStatus,
+		InsecureSkipVerify:  defaults.InsecureSkipVerify,
 		Logger:              defaults.Logger,
 		MetricsCollector:    defaults.MetricsCollector,
 		MaxResponseBodySize: defaults.MaxResponseBodySize,
@@ -67,10 +99,49 @@ func mergeConfig(provided, defaults Config) Config {
 		result.Certificates = cloneCertificates(provided.Certificates)
 	}
 
+	if provided.TLS != nil {
+		result.TLS = provided.TLS
+	}
+
+	if provided.Proxy != nil {
+		result.Proxy = provided.Proxy
+	}
+
+	if provided.DialContext != nil {
+		result.DialContext = provided.DialContext
+	}
+
+	if len(provided.DialOverrides) > 0 {
+		result.DialOverrides = make(map[string]DialTarget, len(provided.DialOverrides))
+		for k, v := range provided.DialOverrides {
+			result.DialOverrides[k] = v
+		}
+	}
+
+	if provided.DNS != nil {
+		result.DNS = provided.DNS
+	}
+
+	if provided.Transport != nil {
+		result.Transport = provided.Transport
+	}
+
+	if provided.Tracing != nil {
+		result.Tracing = provided.Tracing
+	}
+
+	if provided.Redaction != nil {
+		result.Redaction = provided.Redaction
+	}
+
 	if provided.HTTPTransport != nil {
 		result.HTTPTransport = provided.HTTPTransport
 	}
 
+	if len(provided.TransportMiddleware) > 0 {
+		result.TransportMiddleware = append([]RoundTripperMiddleware(nil), provided.TransportMiddleware...)
+	}
+
 	if provided.Adapter != nil {
 		result.Adapter = provided.Adapter
 	}
@@ -89,10 +160,16 @@ func mergeConfig(provided, defaults Config) Config {
 		result.Logger = provided.Logger
 	}
 
+	result.LogLevel = provided.LogLevel
+
 	if provided.MetricsCollector != nil {
 		result.MetricsCollector = provided.MetricsCollector
 	}
 
+	if provided.PathNormalizer != nil {
+		result.PathNormalizer = provided.PathNormalizer
+	}
+
 	if provided.MaxResponseBodySize > 0 {
 		result.MaxResponseBodySize = provided.MaxResponseBodySize
 	}
@@ -105,6 +182,21 @@ func mergeConfig(provided, defaults Config) Config {
 		result.Retry = provided.Retry
 	}
 
+	result.EnableTrace = provided.EnableTrace
+	result.DebugMode = provided.DebugMode
+
+	if provided.Debug != nil {
+		result.Debug = provided.Debug
+	}
+
+	if provided.HAR != nil {
+		result.HAR = provided.HAR
+	}
+
+	if provided.Fallback != nil {
+		result.Fallback = provided.Fallback
+	}
+
 	return result
 }
 
@@ -131,4 +223,3 @@ func cloneCertificates(certificates []CertificateConfig) []CertificateConfig {
 
 	return result
 }
-
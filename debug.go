@@ -0,0 +1,183 @@
+package vecto
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultDebugMaxBodySize = 500
+
+// DebugConfig configures the output of DebugMode.
+type DebugConfig struct {
+	// Writer receives the debug output of every request.
+	// Default: nil (the output is logged with Logger.Debug)
+	Writer io.Writer
+
+	// MaxBodySize is the number of bytes of each body shown before it is
+	// truncated. A negative value shows bodies in full.
+	// Default: 500
+	MaxBodySize int
+
+	// DisablePrettyPrint shows bodies as sent instead of indenting JSON and XML.
+	// Default: false
+	DisablePrettyPrint bool
+}
+
+func (c *DebugConfig) maxBodySize() int {
+	if c == nil || c.MaxBodySize == 0 {
+		return defaultDebugMaxBodySize
+	}
+	return c.MaxBodySize
+}
+
+// debugEnabled reports whether the debug output of a request is written.
+// RequestOptions.Debug overrides Config.DebugMode.
+func (v *Vecto) debugEnabled(options *RequestOptions) bool {
+	if options != nil && options.Debug != nil {
+		return *options.Debug
+	}
+	return v.config.DebugMode
+}
+
+// formatDebugBody formats a body for the debug output: redacted, indented if
+// it is JSON or XML, and truncated to the configured size.
+func formatDebugBody(body []byte, contentType string, rd *redactor, config *DebugConfig) string {
+	body = rd.body(body)
+
+	if config == nil || !config.DisablePrettyPrint {
+		body = prettyBody(body, contentType)
+	}
+
+	limit := config.maxBodySize()
+	if limit < 0 || len(body) <= limit {
+		return string(body)
+	}
+
+	// Cut at a rune boundary so a multi-byte character is not split. Bodies that
+	// are not UTF-8 are cut at most utf8.UTFMax-1 bytes early.
+	cut := limit
+	for i := 0; i < utf8.UTFMax-1 && cut > 0 && !utf8.RuneStart(body[cut]); i++ {
+		cut--
+	}
+
+	return fmt.Sprintf("%s... (truncated, %d bytes total)", body[:cut], len(body))
+}
+
+// prettyBody indents a JSON or XML body, detected from contentType or, if it
+// names neither, from the first byte. Other bodies are returned unchanged.
+func prettyBody(body []byte, contentType string) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return body
+	}
+
+	contentType = strings.ToLower(contentType)
+	isJSON := strings.Contains(contentType, "json")
+	isXML := strings.Contains(contentType, "xml")
+	if !isJSON && !isXML {
+		isJSON = trimmed[0] == '{' || trimmed[0] == '['
+		isXML = trimmed[0] == '<'
+	}
+
+	var b bytes.Buffer
+	switch {
+	case isJSON:
+		if err := json.Indent(&b, trimmed, "", "  "); err != nil {
+			return body
+		}
+	case isXML:
+		if err := indentXML(&b, trimmed); err != nil {
+			return body
+		}
+	default:
+		return body
+	}
+
+	return b.Bytes()
+}
+
+// indentXML writes body with one element per line. Raw tokens are used so
+// namespace prefixes are kept as written.
+func indentXML(w *bytes.Buffer, body []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	// open is true while the last element written has no child yet, so its
+	// text and end tag stay on its line.
+	open := false
+
+	newline := func() {
+		if w.Len() > 0 {
+			w.WriteByte('\n')
+		}
+		w.WriteString(strings.Repeat("  ", depth))
+	}
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			newline()
+			w.WriteString("<" + xmlName(t.Name))
+			for _, attr := range t.Attr {
+				w.WriteString(" " + xmlName(attr.Name) + `="`)
+				xml.EscapeText(w, []byte(attr.Value))
+				w.WriteByte('"')
+			}
+			w.WriteByte('>')
+			depth++
+			open = true
+		case xml.EndElement:
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("unexpected end element %s", xmlName(t.Name))
+			}
+			if !open {
+				newline()
+			}
+			w.WriteString("</" + xmlName(t.Name) + ">")
+			open = false
+		case xml.CharData:
+			text := bytes.TrimSpace(t)
+			if len(text) == 0 {
+				continue
+			}
+			if !open {
+				newline()
+			}
+			xml.EscapeText(w, text)
+		case xml.Comment:
+			newline()
+			w.WriteString("<!--" + string(t) + "-->")
+			open = false
+		case xml.ProcInst:
+			newline()
+			w.WriteString("<?" + t.Target)
+			if len(t.Inst) > 0 {
+				w.WriteString(" " + string(t.Inst))
+			}
+			w.WriteString("?>")
+		case xml.Directive:
+			newline()
+			w.WriteString("<!" + string(t) + ">")
+		}
+	}
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
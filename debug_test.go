@@ -0,0 +1,120 @@
+package vecto
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugWriter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1,"tags":["a","b"]}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	v, err := New(Config{
+		BaseURL:   srv.URL,
+		DebugMode: true,
+		Debug:     &DebugConfig{Writer: &buf},
+	})
+	require.NoError(t, err)
+
+	_, err = v.Post(context.Background(), "/users", &RequestOptions{
+		Data: map[string]string{"name": "John"},
+	})
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "DEBUG INFO")
+	assert.Contains(t, out, "Request Body:\n{\n  \"name\": \"John\"\n}")
+	assert.Contains(t, out, "Response Body:\n{\n  \"id\": 1,\n  \"tags\": [\n    \"a\",\n    \"b\"\n  ]\n}")
+	assert.Contains(t, out, `--data-raw '{"name":"John"}'`)
+	assert.NotContains(t, out, "map[")
+}
+
+func TestDebugToggle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	v, err := New(Config{BaseURL: srv.URL, Debug: &DebugConfig{Writer: &buf}})
+	require.NoError(t, err)
+
+	_, err = v.Get(context.Background(), "/", nil)
+	require.NoError(t, err)
+	assert.Empty(t, buf.String())
+
+	on := true
+	_, err = v.Get(context.Background(), "/", &RequestOptions{Debug: &on})
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(buf.String(), "DEBUG INFO"))
+
+	v.config.DebugMode = true
+	off := false
+	_, err = v.Get(context.Background(), "/", &RequestOptions{Debug: &off})
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(buf.String(), "DEBUG INFO"))
+}
+
+func TestFormatDebugBody(t *testing.T) {
+	rd := newRedactor(&RedactionConfig{BodyFields: []string{"password"}})
+
+	t.Run("json", func(t *testing.T) {
+		body := formatDebugBody([]byte(`{"user":"u","password":"p"}`), "application/json", rd, nil)
+		assert.Equal(t, "{\n  \"password\": \"[REDACTED]\",\n  \"user\": \"u\"\n}", body)
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		body := formatDebugBody([]byte(`<?xml version="1.0"?><user id="1"><name>John</name><tags><tag>a</tag></tags></user>`), "text/xml", rd, nil)
+		assert.Equal(t, `<?xml version="1.0"?>
+<user id="1">
+  <name>John</name>
+  <tags>
+    <tag>a</tag>
+  </tags>
+</user>`, body)
+	})
+
+	t.Run("detected from content", func(t *testing.T) {
+		assert.Equal(t, "[\n  1\n]", formatDebugBody([]byte(`[1]`), "", rd, nil))
+		assert.Equal(t, "plain text", formatDebugBody([]byte("plain text"), "text/plain", rd, nil))
+		assert.Equal(t, "{invalid", formatDebugBody([]byte("{invalid"), "application/json", rd, nil))
+	})
+
+	t.Run("pretty print disabled", func(t *testing.T) {
+		body := formatDebugBody([]byte(`{"a":1}`), "application/json", rd, &DebugConfig{DisablePrettyPrint: true})
+		assert.Equal(t, `{"a":1}`, body)
+	})
+
+	t.Run("truncation", func(t *testing.T) {
+		long := []byte(strings.Repeat("x", 600))
+
+		body := formatDebugBody(long, "text/plain", rd, nil)
+		assert.Equal(t, strings.Repeat("x", 500)+"... (truncated, 600 bytes total)", body)
+
+		body = formatDebugBody(long, "text/plain", rd, &DebugConfig{MaxBodySize: 10})
+		assert.Equal(t, strings.Repeat("x", 10)+"... (truncated, 600 bytes total)", body)
+
+		body = formatDebugBody(long, "text/plain", rd, &DebugConfig{MaxBodySize: -1})
+		assert.Equal(t, string(long), body)
+	})
+
+	t.Run("truncation keeps runes whole", func(t *testing.T) {
+		body := formatDebugBody([]byte("aé€b"), "text/plain", rd, &DebugConfig{MaxBodySize: 2})
+		assert.Equal(t, "a... (truncated, 7 bytes total)", body)
+
+		body = formatDebugBody([]byte("aé€b"), "text/plain", rd, &DebugConfig{MaxBodySize: 5})
+		assert.Equal(t, "aé... (truncated, 7 bytes total)", body)
+
+		body = formatDebugBody([]byte("aé€b"), "text/plain", rd, &DebugConfig{MaxBodySize: 6})
+		assert.Equal(t, "aé€... (truncated, 7 bytes total)", body)
+	})
+}
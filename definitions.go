@@ -6,6 +6,9 @@ import (
 	"time"
 )
 
+// CertificateConfig is a PEM certificate and key pair. Each entry is both trusted
+// as a root CA and presented as a client certificate; use TLSConfig to configure
+// CA bundles and client certificates separately.
 type CertificateConfig struct {
 	Cert string
 	Key  string
@@ -21,30 +24,67 @@ type ValidateStatusFunc func(res *Response) bool
 type RequestMetrics struct {
 	// Method is the HTTP method (GET, POST, etc.)
 	Method string
-	
+
 	// URL is the normalized request URL (host + path, without query params).
 	URL string
-	
+
+	// Route is the low-cardinality route of the request, such as "/users/{id}"
+	// (empty if the request has no path template and no PathNormalizer applies).
+	Route string
+
 	// FullURL is the complete request URL including query params.
 	FullURL string
-	
+
 	// Duration is the total request duration.
 	Duration time.Duration
-	
+
 	// StatusCode is the HTTP status code (0 if request failed before receiving response).
 	StatusCode int
-	
+
 	// Error is the error that occurred, if any.
 	Error error
-	
-	// RequestSize is the size of the request body in bytes (0 if no body).
+
+	// RequestSize is the number of request body bytes sent in the last attempt (0 if no body).
 	RequestSize int64
-	
+
 	// ResponseSize is the size of the response body in bytes (0 if no response).
 	ResponseSize int64
-	
+
 	// Success indicates if the request was considered successful.
 	Success bool
+
+	// Fallback indicates the response was produced by a fallback instead of the server.
+	Fallback bool
+
+	// Attempts is the number of attempts sent through the Client (0 if none was sent).
+	Attempts int
+
+	// AttemptDurations holds the duration of every attempt, in order.
+	AttemptDurations []time.Duration
+
+	// RateLimitWait is the time spent waiting for a rate limiter. vecto has no
+	// rate limiter of its own: this is only set by a TransportMiddleware calling
+	// RecordRateLimitWait, and is 0 otherwise.
+	RateLimitWait time.Duration
+
+	// CircuitBreakerState is the state of the circuit breaker when the request
+	// was admitted or rejected (empty if circuit breaking is disabled).
+	CircuitBreakerState string
+
+	// CacheHit indicates the response was served from a cache. vecto has no
+	// response cache of its own: this is only set by a TransportMiddleware
+	// calling RecordCacheHit, and is false otherwise.
+	CacheHit bool
+
+	// Protocol is the protocol of the response, e.g. "HTTP/1.1" or "HTTP/2.0".
+	Protocol string
+
+	// Trace holds the timing phases of the last attempt (nil unless EnableTrace is set).
+	Trace *TraceInfo
+
+	// InjectedFaults names the faults a FaultInjector injected, one per
+	// affected attempt, in order.
+	InjectedFaults []string
 }
 
 // MetricsCollector is the interface for collecting HTTP request metrics.
@@ -55,23 +95,64 @@ type MetricsCollector interface {
 	RecordRequest(ctx context.Context, metrics RequestMetrics)
 }
 
+// InFlightRecorder is implemented by a MetricsCollector that tracks the
+// requests in progress. The route is the request route, or the normalized
+// request URL if the request has no route.
+type InFlightRecorder interface {
+	RequestStarted(ctx context.Context, method, route string)
+	RequestFinished(ctx context.Context, method, route string)
+}
+
+// RetryRecorder is implemented by a MetricsCollector that counts retries.
+// The route is as for InFlightRecorder, and attempt is the number of the
+// attempt that failed, starting at 1.
+type RetryRecorder interface {
+	RecordRetry(ctx context.Context, method, route string, attempt int)
+}
+
+// CircuitBreakerStateRecorder is implemented by a MetricsCollector that
+// tracks circuit breaker states. It is called with StateClosed when a breaker
+// is created, and on every state change.
+type CircuitBreakerStateRecorder interface {
+	RecordCircuitBreakerState(key string, state CircuitBreakerState)
+}
+
+// Config configures a Vecto client.
+//
+// An HTTPTransport that is an *http.Transport is cloned before vecto
+// configures it, so CloseIdleConnections and later changes on the supplied
+// instance do not affect the client. Other round trippers are used as-is.
 type Config struct {
-	BaseURL                string
-	Timeout                time.Duration
-	Headers                map[string]string
-	Certificates           []CertificateConfig
-	HTTPTransport          *http.Transport
-	Adapter                AdapterFunc
-	RequestTransform       RequestTransformFunc
-	ValidateStatus         ValidateStatusFunc
-	InsecureSkipVerify     bool
-	Logger                 Logger
-	MetricsCollector       MetricsCollector
-	MaxResponseBodySize    int64
-	CircuitBreaker         *CircuitBreakerConfig
-	Retry                  *RetryConfig
-	EnableTrace            bool
-	DebugMode              bool
+	BaseURL             string
+	Timeout             time.Duration
+	Headers             map[string]string
+	Certificates        []CertificateConfig
+	TLS                 *TLSConfig
+	Proxy               *ProxyConfig
+	DialContext         DialContextFunc
+	DialOverrides       map[string]DialTarget
+	DNS                 *DNSConfig
+	Transport           *TransportConfig
+	Tracing             *TracingConfig
+	Redaction           *RedactionConfig
+	HTTPTransport       http.RoundTripper
+	TransportMiddleware []RoundTripperMiddleware
+	Adapter             AdapterFunc
+	RequestTransform    RequestTransformFunc
+	ValidateStatus      ValidateStatusFunc
+	InsecureSkipVerify  bool
+	Logger              Logger
+	LogLevel            LogLevel
+	MetricsCollector    MetricsCollector
+	PathNormalizer      PathNormalizer
+	MaxResponseBodySize int64
+	CircuitBreaker      *CircuitBreakerConfig
+	Retry               *RetryConfig
+	EnableTrace         bool
+	DebugMode           bool
+	Debug               *DebugConfig
+	HAR                 *HARRecorder
+	Fallback            FallbackFunc
 }
 
 type Client interface {
@@ -99,4 +180,7 @@ type RequestOptions struct {
 	PathParams       map[string]string
 	FormData         map[string]string
 	QueryStruct      interface{}
+	Fallback         FallbackFunc
+	Debug            *bool
+	Middleware       []Interceptor
 }
@@ -0,0 +1,117 @@
+package vecto
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	defaultDialTimeout   = 30 * time.Second
+	defaultDialKeepAlive = 30 * time.Second
+)
+
+// DialContextFunc dials a network connection, like net.Dialer.DialContext.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// DialTarget is the address a host is dialed at instead of its own.
+type DialTarget struct {
+	// Network is "tcp", "tcp4", "tcp6" or "unix".
+	Network string
+
+	// Address is a "host:port" pair for TCP or a socket path for "unix".
+	Address string
+}
+
+// UnixSocket returns a DialTarget for the Unix domain socket at path.
+//
+// Example:
+//
+//	vecto.New(vecto.Config{
+//	    BaseURL:       "http://docker/v1.43",
+//	    DialOverrides: map[string]vecto.DialTarget{"docker": vecto.UnixSocket("/var/run/docker.sock")},
+//	})
+func UnixSocket(path string) DialTarget {
+	return DialTarget{Network: "unix", Address: path}
+}
+
+func validateDialOverrides(overrides map[string]DialTarget) error {
+	for host, target := range overrides {
+		if host == "" {
+			return fmt.Errorf("dial override host cannot be empty")
+		}
+
+		switch target.Network {
+		case "tcp", "tcp4", "tcp6", "unix":
+		default:
+			return fmt.Errorf("dial override for %q: unsupported network %q", host, target.Network)
+		}
+
+		if target.Address == "" {
+			return fmt.Errorf("dial override for %q: address cannot be empty", host)
+		}
+	}
+
+	return nil
+}
+
+// dialer routes connections to their dial override, if any, and to the
+// configured DialContext otherwise. With a resolver, TCP host names are
+// resolved before dialing.
+type dialer struct {
+	overrides map[string]DialTarget
+	dial      DialContextFunc
+	resolver  *dnsResolver
+	// timeout bounds resolving and dialing a host name when the context has
+	// no deadline, so that it can be shared between its addresses.
+	timeout time.Duration
+}
+
+func newDialer(overrides map[string]DialTarget, dial DialContextFunc, resolver *dnsResolver, timeout time.Duration) *dialer {
+	return &dialer{
+		overrides: overrides,
+		dial:      dial,
+		resolver:  resolver,
+		timeout:   timeout,
+	}
+}
+
+// lookup finds the override for addr, preferring "host:port" entries over "host" ones.
+func (d *dialer) lookup(addr string) (DialTarget, bool) {
+	if target, ok := d.overrides[addr]; ok {
+		return target, true
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return DialTarget{}, false
+	}
+
+	target, ok := d.overrides[host]
+	return target, ok
+}
+
+func (d *dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if target, ok := d.lookup(addr); ok {
+		network, addr = target.Network, target.Address
+	}
+
+	if d.resolver != nil && network != "unix" {
+		if _, ok := ctx.Deadline(); !ok && d.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d.timeout)
+			defer cancel()
+		}
+		return dialResolved(ctx, d.resolver, d.dial, network, addr)
+	}
+
+	return d.dial(ctx, network, addr)
+}
+
+func defaultDialContext() DialContextFunc {
+	return (&net.Dialer{
+		Timeout:   defaultDialTimeout,
+		KeepAlive: defaultDialKeepAlive,
+	}).DialContext
+}
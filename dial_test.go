@@ -0,0 +1,132 @@
+package vecto
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startUnixSocketServer(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "vecto")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "api.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(listener)
+	t.Cleanup(func() { srv.Close() })
+
+	return socket
+}
+
+func TestValidateDialOverrides(t *testing.T) {
+	assert.NoError(t, validateDialOverrides(map[string]DialTarget{
+		"docker":         UnixSocket("/var/run/docker.sock"),
+		"api.local:8080": {Network: "tcp", Address: "127.0.0.1:9000"},
+	}))
+
+	err := validateDialOverrides(map[string]DialTarget{"docker": {Network: "udp", Address: "x"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `dial override for "docker": unsupported network "udp"`)
+
+	err = validateDialOverrides(map[string]DialTarget{"docker": {Network: "unix"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "address cannot be empty")
+}
+
+func TestDialOverrides(t *testing.T) {
+	t.Run("targets unix socket with middleware, retries and metrics", func(t *testing.T) {
+		var calls int32
+		socket := startUnixSocketServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(r.URL.Path))
+		}))
+
+		collector := &mockMetricsCollector{}
+		v, err := New(Config{
+			BaseURL:          "http://docker/v1.43",
+			DialOverrides:    map[string]DialTarget{"docker": UnixSocket(socket)},
+			MetricsCollector: collector,
+			Retry: &RetryConfig{
+				MaxAttempts: 3,
+				WaitTime:    time.Millisecond,
+				Backoff:     FixedBackoff,
+			},
+		})
+		require.NoError(t, err)
+
+		middlewareCalled := false
+		v.UseRequest(func(ctx context.Context, req *Request) (*Request, error) {
+			middlewareCalled = true
+			return req, nil
+		})
+
+		res, err := v.Get(context.Background(), "/containers/json", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "/v1.43/containers/json", res.String())
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+		assert.True(t, middlewareCalled)
+		require.Len(t, collector.requests, 1)
+		assert.Equal(t, "http://docker/v1.43/containers/json", collector.requests[0].URL)
+	})
+
+	t.Run("host and port override takes precedence", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("tcp target"))
+		}))
+		defer srv.Close()
+
+		v, err := New(Config{
+			BaseURL: "http://api.local:8080",
+			DialOverrides: map[string]DialTarget{
+				"api.local":      UnixSocket("/nonexistent.sock"),
+				"api.local:8080": {Network: "tcp", Address: srv.Listener.Addr().String()},
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "tcp target", res.String())
+	})
+
+	t.Run("custom DialContext hook", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+		defer srv.Close()
+
+		var dialed []string
+		v, err := New(Config{
+			BaseURL: "http://service.internal",
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dialed = append(dialed, addr)
+				var d net.Dialer
+				return d.DialContext(ctx, network, srv.Listener.Addr().String())
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", res.String())
+		assert.Equal(t, []string{"service.internal:80"}, dialed)
+	})
+}
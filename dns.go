@@ -0,0 +1,333 @@
+package vecto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDNSCacheEntries = 1024
+
+	// minDialAddrTimeout is the least time an address is given when the time
+	// left is shared between several addresses, as for net.Dialer.
+	minDialAddrTimeout = 2 * time.Second
+)
+
+// Resolver resolves host names to IP addresses. *net.Resolver implements it.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// DNSConfig configures how host names are resolved before dialing.
+type DNSConfig struct {
+	// Resolver performs the lookups. Default: net.DefaultResolver
+	Resolver Resolver
+
+	// Hosts maps host names to static IP addresses, like curl --resolve.
+	// Static entries are never sent to the Resolver.
+	Hosts map[string][]string
+
+	// CacheTTL is how long successful lookups are cached.
+	// Default: 0 (lookups are not cached)
+	CacheTTL time.Duration
+
+	// NegativeCacheTTL is how long "host not found" results are cached.
+	// Default: 0 (failed lookups are not cached)
+	NegativeCacheTTL time.Duration
+
+	// MaxCacheEntries bounds the number of cached lookups. When the cache is
+	// full, expired entries are dropped first, then the one closest to expiring.
+	// Default: 1024
+	MaxCacheEntries int
+}
+
+func validateDNSConfig(config *DNSConfig) error {
+	if config == nil {
+		return nil
+	}
+
+	for host, addrs := range config.Hosts {
+		if host == "" {
+			return fmt.Errorf("static host name cannot be empty")
+		}
+		if len(addrs) == 0 {
+			return fmt.Errorf("static host %q: at least one address is required", host)
+		}
+		for i, addr := range addrs {
+			if net.ParseIP(addr) == nil {
+				return fmt.Errorf("static host %q: address %d (%q) is not an IP address", host, i, addr)
+			}
+		}
+	}
+
+	if config.CacheTTL < 0 {
+		return fmt.Errorf("cache TTL cannot be negative")
+	}
+
+	if config.NegativeCacheTTL < 0 {
+		return fmt.Errorf("negative cache TTL cannot be negative")
+	}
+
+	if config.MaxCacheEntries < 0 {
+		return fmt.Errorf("max cache entries cannot be negative")
+	}
+
+	return nil
+}
+
+type dnsCacheEntry struct {
+	addrs   []net.IPAddr
+	err     error
+	expires time.Time
+}
+
+// dnsResolver resolves host names through static entries, the cache and the Resolver.
+type dnsResolver struct {
+	mu               sync.Mutex
+	resolver         Resolver
+	hosts            map[string][]net.IPAddr
+	cache            map[string]dnsCacheEntry
+	cacheTTL         time.Duration
+	negativeCacheTTL time.Duration
+	maxCacheEntries  int
+	nextPrune        time.Time
+}
+
+func newDNSResolver(config *DNSConfig) *dnsResolver {
+	r := &dnsResolver{
+		resolver:         config.Resolver,
+		hosts:            make(map[string][]net.IPAddr, len(config.Hosts)),
+		cache:            make(map[string]dnsCacheEntry, 16),
+		cacheTTL:         config.CacheTTL,
+		negativeCacheTTL: config.NegativeCacheTTL,
+		maxCacheEntries:  config.MaxCacheEntries,
+	}
+
+	if r.resolver == nil {
+		r.resolver = net.DefaultResolver
+	}
+
+	if r.maxCacheEntries == 0 {
+		r.maxCacheEntries = defaultDNSCacheEntries
+	}
+
+	for host, addrs := range config.Hosts {
+		ipAddrs := make([]net.IPAddr, 0, len(addrs))
+		for _, addr := range addrs {
+			if ip := net.ParseIP(addr); ip != nil {
+				ipAddrs = append(ipAddrs, net.IPAddr{IP: ip})
+			}
+		}
+		r.hosts[strings.ToLower(host)] = ipAddrs
+	}
+
+	return r
+}
+
+// resolve returns the addresses for host and whether they came from a static
+// entry or the cache. DNS events are reported to the client trace in ctx; a
+// *net.Resolver reports the lookups it performs itself.
+func (r *dnsResolver) resolve(ctx context.Context, host string) ([]net.IPAddr, bool, error) {
+	key := strings.ToLower(host)
+
+	if entry, ok := r.cached(key); ok {
+		traceDNS(ctx, host, func() ([]net.IPAddr, error) { return entry.addrs, entry.err })
+		markDNSCacheHit(ctx)
+		return entry.addrs, true, entry.err
+	}
+
+	if _, ok := r.resolver.(*net.Resolver); ok {
+		addrs, err := r.query(ctx, key)
+		return addrs, false, err
+	}
+
+	var addrs []net.IPAddr
+	err := traceDNS(ctx, host, func() ([]net.IPAddr, error) {
+		var err error
+		addrs, err = r.query(ctx, key)
+		return addrs, err
+	})
+
+	return addrs, false, err
+}
+
+// traceDNS reports lookup to the client trace in ctx.
+func traceDNS(ctx context.Context, host string, lookup func() ([]net.IPAddr, error)) error {
+	trace := httptrace.ContextClientTrace(ctx)
+
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+
+	addrs, err := lookup()
+
+	if trace != nil && trace.DNSDone != nil {
+		trace.DNSDone(httptrace.DNSDoneInfo{Addrs: addrs, Err: err})
+	}
+
+	return err
+}
+
+// cached returns the static or cached result for key, if there is one.
+func (r *dnsResolver) cached(key string) (dnsCacheEntry, bool) {
+	if addrs, ok := r.hosts[key]; ok {
+		return dnsCacheEntry{addrs: addrs}, true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.cache[key]
+	if ok && time.Now().After(entry.expires) {
+		delete(r.cache, key)
+		return dnsCacheEntry{}, false
+	}
+
+	return entry, ok
+}
+
+// query sends the lookup for key to the Resolver and caches the result.
+func (r *dnsResolver) query(ctx context.Context, key string) ([]net.IPAddr, error) {
+	addrs, err := r.resolver.LookupIPAddr(ctx, key)
+	if err == nil && len(addrs) == 0 {
+		err = &net.DNSError{Err: "no such host", Name: key, IsNotFound: true}
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil && r.cacheTTL > 0:
+		r.store(key, dnsCacheEntry{addrs: addrs, expires: now.Add(r.cacheTTL)})
+	case isDNSNotFound(err) && r.negativeCacheTTL > 0:
+		r.store(key, dnsCacheEntry{err: err, expires: now.Add(r.negativeCacheTTL)})
+	}
+
+	return addrs, err
+}
+
+// store caches entry for key. Expired entries are pruned once per TTL, and
+// when the cache is full.
+func (r *dnsResolver) store(key string, entry dnsCacheEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := r.cache[key]; !ok && len(r.cache) >= r.maxCacheEntries {
+		r.pruneUnsafe(now, true)
+	} else if now.After(r.nextPrune) {
+		r.pruneUnsafe(now, false)
+	}
+
+	r.cache[key] = entry
+}
+
+// pruneUnsafe drops the expired entries. With evict, the entry closest to
+// expiring is dropped too if none expired.
+func (r *dnsResolver) pruneUnsafe(now time.Time, evict bool) {
+	var soonestKey string
+	var soonest time.Time
+	pruned := false
+
+	for key, entry := range r.cache {
+		if now.After(entry.expires) {
+			delete(r.cache, key)
+			pruned = true
+			continue
+		}
+		if soonestKey == "" || entry.expires.Before(soonest) {
+			soonestKey, soonest = key, entry.expires
+		}
+	}
+
+	if evict && !pruned && soonestKey != "" {
+		delete(r.cache, soonestKey)
+	}
+
+	r.nextPrune = now.Add(max(r.cacheTTL, r.negativeCacheTTL))
+}
+
+func isDNSNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// dialResolved resolves the host in addr and dials the addresses in order
+// until one succeeds. When ctx has a deadline, each address gets a share of
+// the time left, so an unresponsive address does not use it all up.
+func dialResolved(ctx context.Context, resolver *dnsResolver, dial DialContextFunc, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return dial(ctx, network, addr)
+	}
+
+	addrs, _, err := resolver.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]net.IPAddr, 0, len(addrs))
+	for _, ipAddr := range addrs {
+		if matchesNetwork(network, ipAddr.IP) {
+			candidates = append(candidates, ipAddr)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, &net.AddrError{Err: "no suitable address found", Addr: host}
+	}
+
+	var firstErr error
+	for i, ipAddr := range candidates {
+		conn, err := dialAddr(ctx, dial, network, net.JoinHostPort(ipAddr.String(), port), len(candidates)-i)
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, firstErr
+}
+
+// dialAddr dials addr, one of remaining addresses left to try, with its share
+// of the time left in ctx.
+func dialAddr(ctx context.Context, dial DialContextFunc, network, addr string, remaining int) (net.Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok && remaining > 1 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, partialDeadline(time.Now(), deadline, remaining))
+		defer cancel()
+	}
+
+	return dial(ctx, network, addr)
+}
+
+// partialDeadline returns the deadline of one of remaining addresses: an
+// equal share of the time left, but at least minDialAddrTimeout.
+func partialDeadline(now, deadline time.Time, remaining int) time.Time {
+	timeLeft := deadline.Sub(now)
+	timeout := timeLeft / time.Duration(remaining)
+	if timeout < minDialAddrTimeout {
+		timeout = min(minDialAddrTimeout, timeLeft)
+	}
+	return now.Add(timeout)
+}
+
+func matchesNetwork(network string, ip net.IP) bool {
+	switch network {
+	case "tcp4":
+		return ip.To4() != nil
+	case "tcp6":
+		return ip.To4() == nil
+	default:
+		return true
+	}
+}
@@ -0,0 +1,349 @@
+package vecto
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockResolver struct {
+	calls int32
+	addrs []net.IPAddr
+	err   error
+}
+
+func (m *mockResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	atomic.AddInt32(&m.calls, 1)
+	return m.addrs, m.err
+}
+
+type resolverFunc func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+func (f resolverFunc) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return f(ctx, host)
+}
+
+func newDNSTestServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	return srv, u.Port()
+}
+
+func TestValidateDNSConfig(t *testing.T) {
+	assert.NoError(t, validateDNSConfig(nil))
+	assert.NoError(t, validateDNSConfig(&DNSConfig{
+		Hosts:    map[string][]string{"api.test": {"127.0.0.1", "::1"}},
+		CacheTTL: time.Minute,
+	}))
+
+	err := validateDNSConfig(&DNSConfig{Hosts: map[string][]string{"api.test": {"not-an-ip"}}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `static host "api.test": address 0 ("not-an-ip") is not an IP address`)
+
+	err = validateDNSConfig(&DNSConfig{Hosts: map[string][]string{"api.test": nil}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "at least one address is required")
+
+	err = validateDNSConfig(&DNSConfig{NegativeCacheTTL: -time.Second})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "negative cache TTL cannot be negative")
+
+	err = validateDNSConfig(&DNSConfig{MaxCacheEntries: -1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max cache entries cannot be negative")
+
+	_, err = New(Config{DNS: &DNSConfig{CacheTTL: -time.Second}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid DNS config")
+}
+
+func TestDNSResolution(t *testing.T) {
+	t.Run("static hosts skip the resolver", func(t *testing.T) {
+		_, port := newDNSTestServer(t)
+		resolver := &mockResolver{err: errors.New("should not be called")}
+
+		v, err := New(Config{
+			BaseURL:     "http://api.test:" + port,
+			EnableTrace: true,
+			DNS: &DNSConfig{
+				Resolver: resolver,
+				Hosts:    map[string][]string{"API.test": {"127.0.0.1"}},
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "api.test:"+port, res.String())
+		assert.Equal(t, int32(0), atomic.LoadInt32(&resolver.calls))
+		require.NotNil(t, res.TraceInfo)
+		assert.True(t, res.TraceInfo.DNSCacheHit)
+	})
+
+	t.Run("passes the request context to the resolver", func(t *testing.T) {
+		type ctxKey struct{}
+		var got any
+		var starts int32
+		resolver := resolverFunc(func(ctx context.Context, host string) ([]net.IPAddr, error) {
+			got = ctx.Value(ctxKey{})
+			return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+		})
+		r := newDNSResolver(&DNSConfig{Resolver: resolver})
+
+		ctx := context.WithValue(context.Background(), ctxKey{}, "trace-id")
+		ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+			DNSStart: func(httptrace.DNSStartInfo) { atomic.AddInt32(&starts, 1) },
+		})
+
+		_, _, err := r.resolve(ctx, "api.test")
+		require.NoError(t, err)
+		assert.Equal(t, "trace-id", got)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&starts))
+	})
+
+	t.Run("reports net.Resolver lookups once", func(t *testing.T) {
+		var starts int32
+		r := newDNSResolver(&DNSConfig{})
+
+		ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+			DNSStart: func(httptrace.DNSStartInfo) { atomic.AddInt32(&starts, 1) },
+		})
+
+		_, _, err := r.resolve(ctx, "localhost")
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&starts))
+	})
+
+	t.Run("caches successful lookups", func(t *testing.T) {
+		_, port := newDNSTestServer(t)
+		resolver := &mockResolver{addrs: []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}}
+
+		v, err := New(Config{
+			BaseURL:       "http://api.test:" + port,
+			EnableTrace:   true,
+			HTTPTransport: &http.Transport{DisableKeepAlives: true},
+			DNS:           &DNSConfig{Resolver: resolver, CacheTTL: time.Minute},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		require.NotNil(t, res.TraceInfo)
+		assert.False(t, res.TraceInfo.DNSCacheHit)
+
+		res, err = v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.True(t, res.TraceInfo.DNSCacheHit)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&resolver.calls))
+	})
+
+	t.Run("expires cached lookups", func(t *testing.T) {
+		_, port := newDNSTestServer(t)
+		resolver := &mockResolver{addrs: []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}}
+
+		v, err := New(Config{
+			BaseURL:       "http://api.test:" + port,
+			HTTPTransport: &http.Transport{DisableKeepAlives: true},
+			DNS:           &DNSConfig{Resolver: resolver, CacheTTL: time.Millisecond},
+		})
+		require.NoError(t, err)
+
+		_, err = v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+		_, err = v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(&resolver.calls))
+	})
+
+	t.Run("caches host not found", func(t *testing.T) {
+		resolver := &mockResolver{err: &net.DNSError{Err: "no such host", Name: "missing.test", IsNotFound: true}}
+
+		v, err := New(Config{
+			BaseURL: "http://missing.test",
+			DNS:     &DNSConfig{Resolver: resolver, NegativeCacheTTL: time.Minute},
+		})
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err = v.Get(context.Background(), "/", nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "no such host")
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(&resolver.calls))
+	})
+
+	t.Run("does not cache temporary failures", func(t *testing.T) {
+		resolver := &mockResolver{err: &net.DNSError{Err: "server misbehaving", Name: "flaky.test", IsTemporary: true}}
+
+		v, err := New(Config{
+			BaseURL: "http://flaky.test",
+			DNS:     &DNSConfig{Resolver: resolver, NegativeCacheTTL: time.Minute},
+		})
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err = v.Get(context.Background(), "/", nil)
+			require.Error(t, err)
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&resolver.calls))
+	})
+
+	t.Run("falls through to the next address", func(t *testing.T) {
+		_, port := newDNSTestServer(t)
+
+		v, err := New(Config{
+			BaseURL: "http://api.test:" + port,
+			DNS: &DNSConfig{
+				Hosts: map[string][]string{"api.test": {"127.0.0.2", "127.0.0.1"}},
+			},
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				if host, _, _ := net.SplitHostPort(addr); host == "127.0.0.2" {
+					return nil, errors.New("connection refused")
+				}
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+}
+
+func TestDNSDialTimeout(t *testing.T) {
+	_, port := newDNSTestServer(t)
+
+	var firstTimeout time.Duration
+	v, err := New(Config{
+		BaseURL: "http://api.test:" + port,
+		Timeout: 10 * time.Second,
+		DNS: &DNSConfig{
+			Hosts: map[string][]string{"api.test": {"127.0.0.2", "127.0.0.1"}},
+		},
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if host, _, _ := net.SplitHostPort(addr); host == "127.0.0.2" {
+				deadline, ok := ctx.Deadline()
+				require.True(t, ok, "the client timeout bounds the dial")
+				firstTimeout = time.Until(deadline)
+				return nil, errors.New("connection refused")
+			}
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = v.Get(context.Background(), "/", nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 5*time.Second, firstTimeout, float64(time.Second))
+}
+
+func TestDNSCacheBounds(t *testing.T) {
+	resolver := &mockResolver{addrs: []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}}
+
+	t.Run("evicts the entry closest to expiring when full", func(t *testing.T) {
+		r := newDNSResolver(&DNSConfig{Resolver: resolver, CacheTTL: time.Minute, MaxCacheEntries: 2})
+
+		for _, host := range []string{"a.test", "b.test", "c.test"} {
+			_, _, err := r.resolve(context.Background(), host)
+			require.NoError(t, err)
+		}
+
+		assert.Len(t, r.cache, 2)
+		assert.NotContains(t, r.cache, "a.test")
+		assert.Contains(t, r.cache, "c.test")
+	})
+
+	t.Run("prunes expired entries", func(t *testing.T) {
+		r := newDNSResolver(&DNSConfig{Resolver: resolver, CacheTTL: time.Millisecond})
+		assert.Equal(t, defaultDNSCacheEntries, r.maxCacheEntries)
+
+		for _, host := range []string{"a.test", "b.test"} {
+			_, _, err := r.resolve(context.Background(), host)
+			require.NoError(t, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		_, _, err := r.resolve(context.Background(), "c.test")
+		require.NoError(t, err)
+		assert.Len(t, r.cache, 1)
+		assert.Contains(t, r.cache, "c.test")
+	})
+}
+
+func TestDialResolvedDeadlines(t *testing.T) {
+	resolver := newDNSResolver(&DNSConfig{
+		Hosts: map[string][]string{"api.test": {"127.0.0.2", "127.0.0.3", "127.0.0.1"}},
+	})
+
+	var deadlines []time.Duration
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadlines = append(deadlines, 0)
+		} else {
+			deadlines = append(deadlines, time.Until(deadline))
+		}
+		return nil, errors.New("connection refused")
+	}
+
+	t.Run("shares the time left between the addresses", func(t *testing.T) {
+		deadlines = nil
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		_, err := dialResolved(ctx, resolver, dial, "tcp", "api.test:80")
+		require.Error(t, err)
+		require.Len(t, deadlines, 3)
+		assert.InDelta(t, 10*time.Second, deadlines[0], float64(time.Second))
+		assert.InDelta(t, 15*time.Second, deadlines[1], float64(time.Second))
+		assert.InDelta(t, 30*time.Second, deadlines[2], float64(time.Second))
+	})
+
+	t.Run("gives each address a minimum", func(t *testing.T) {
+		deadlines = nil
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		_, err := dialResolved(ctx, resolver, dial, "tcp", "api.test:80")
+		require.Error(t, err)
+		require.Len(t, deadlines, 3)
+		assert.InDelta(t, minDialAddrTimeout, deadlines[0], float64(100*time.Millisecond))
+	})
+
+	t.Run("leaves the timeout to the dialer without a deadline", func(t *testing.T) {
+		deadlines = nil
+
+		_, err := dialResolved(context.Background(), resolver, dial, "tcp", "api.test:80")
+		require.Error(t, err)
+		assert.Equal(t, []time.Duration{0, 0, 0}, deadlines)
+	})
+}
+
+func TestPartialDeadline(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, now.Add(5*time.Second), partialDeadline(now, now.Add(10*time.Second), 2))
+	assert.Equal(t, now.Add(minDialAddrTimeout), partialDeadline(now, now.Add(3*time.Second), 3))
+	assert.Equal(t, now.Add(time.Second), partialDeadline(now, now.Add(time.Second), 3))
+}
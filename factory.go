@@ -5,6 +5,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"net/http"
+	"slices"
 	"time"
 )
 
@@ -12,12 +13,28 @@ const (
 	defaultIdleConnTimeout = 50 * time.Second
 )
 
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as http.RoundTripper.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// RoundTripperMiddleware wraps an http.RoundTripper with additional behavior.
+// It runs for every attempt sent over the wire, after request middleware,
+// retries and the circuit breaker have been applied.
+type RoundTripperMiddleware func(next http.RoundTripper) http.RoundTripper
+
 type httpClientFactory struct {
 	config Config
+	logger Logger
+	pool   *poolTracker
 }
 
 func newDefaultClient(vecto *Vecto) (client Client, err error) {
 	httpClientFact := newHTTPClientFactory(vecto.config)
+	httpClientFact.logger = vecto.logger
 
 	httpClient, err := httpClientFact.make()
 	if err != nil {
@@ -28,6 +45,7 @@ func newDefaultClient(vecto *Vecto) (client Client, err error) {
 		client:              httpClient,
 		maxResponseBodySize: vecto.config.MaxResponseBodySize,
 		enableTrace:         vecto.config.EnableTrace,
+		pool:                httpClientFact.pool,
 	}
 
 	return client, nil
@@ -35,63 +53,238 @@ func newDefaultClient(vecto *Vecto) (client Client, err error) {
 
 func newHTTPClientFactory(config Config) httpClientFactory {
 	return httpClientFactory{
-		config,
+		config: config,
+	}
+}
+
+func (h *httpClientFactory) getLogger() Logger {
+	if h.logger != nil {
+		return h.logger
+	}
+	if h.config.Logger != nil {
+		return h.config.Logger
 	}
+	return newNoopLogger()
 }
 
 func (h *httpClientFactory) make() (client http.Client, err error) {
-	transport := h.config.HTTPTransport
-	if transport == nil {
-		transport, err = h.getTransportConfig()
-		if err != nil {
-			return client, err
-		}
+	transport, err := h.getTransport()
+	if err != nil {
+		return client, err
 	}
 
 	client = http.Client{
-		Transport: transport,
+		Transport: h.wrapTransport(transport),
 		Timeout:   h.config.Timeout,
 	}
 
 	return client, nil
 }
 
-func (h *httpClientFactory) getTransportConfig() (transport *http.Transport, err error) {
-	if len(h.config.Certificates) == 0 {
-		transport = &http.Transport{
-			IdleConnTimeout: defaultIdleConnTimeout,
+// getTransport returns the round tripper used to send requests.
+// A supplied *http.Transport is cloned before the transport settings and pool
+// tracking are applied so the caller's instance is never modified. Other round
+// trippers are used as-is; validateHTTPTransport rejects transport settings for them.
+func (h *httpClientFactory) getTransport() (http.RoundTripper, error) {
+	switch transport := h.config.HTTPTransport.(type) {
+	case nil:
+		return h.getTransportConfig()
+	case *http.Transport:
+		return h.configureTransport(transport.Clone())
+	default:
+		return transport, nil
+	}
+}
+
+// validateHTTPTransport rejects transport settings combined with an
+// HTTPTransport that is not an *http.Transport, as they cannot be applied to it.
+func validateHTTPTransport(config Config) error {
+	if config.HTTPTransport == nil {
+		return nil
+	}
+	if _, ok := config.HTTPTransport.(*http.Transport); ok {
+		return nil
+	}
+
+	settings := []struct {
+		name string
+		set  bool
+	}{
+		{"Certificates", len(config.Certificates) > 0},
+		{"InsecureSkipVerify", config.InsecureSkipVerify},
+		{"TLS", config.TLS != nil},
+		{"Proxy", config.Proxy != nil},
+		{"DialContext", config.DialContext != nil},
+		{"DialOverrides", len(config.DialOverrides) > 0},
+		{"DNS", config.DNS != nil},
+		{"Transport", config.Transport != nil},
+	}
+
+	for _, setting := range settings {
+		if setting.set {
+			return fmt.Errorf("%s cannot be applied to a custom round tripper (%T), configure it on the round tripper or use an *http.Transport", setting.name, config.HTTPTransport)
 		}
+	}
+
+	return nil
+}
 
-		return transport, err
+// wrapTransport applies the round tripper middleware chain.
+// The first middleware in the list is the outermost one.
+func (h *httpClientFactory) wrapTransport(transport http.RoundTripper) http.RoundTripper {
+	for i := len(h.config.TransportMiddleware) - 1; i >= 0; i-- {
+		mw := h.config.TransportMiddleware[i]
+		if mw == nil {
+			continue
+		}
+		transport = mw(transport)
 	}
 
-	certificates := make([]tls.Certificate, 0, len(h.config.Certificates))
-	caCertPool := x509.NewCertPool()
+	return transport
+}
 
-	for _, certConfig := range h.config.Certificates {
-		ok := caCertPool.AppendCertsFromPEM([]byte(certConfig.Cert))
-		if !ok {
-			return transport, fmt.Errorf("failed to append certificate to pool")
+func (h *httpClientFactory) getTransportConfig() (transport *http.Transport, err error) {
+	transport = &http.Transport{
+		IdleConnTimeout: defaultIdleConnTimeout,
+	}
+
+	return h.configureTransport(transport)
+}
+
+func (h *httpClientFactory) hasTLSSettings() bool {
+	return len(h.config.Certificates) > 0 || h.config.InsecureSkipVerify || h.config.TLS != nil
+}
+
+func (h *httpClientFactory) hasDialSettings() bool {
+	return h.config.DialContext != nil || len(h.config.DialOverrides) > 0 || h.config.DNS != nil
+}
+
+// configureTransport applies the transport settings from the config.
+func (h *httpClientFactory) configureTransport(transport *http.Transport) (*http.Transport, error) {
+	var verify hostVerifyFunc
+	if h.hasTLSSettings() {
+		var err error
+		if verify, err = h.configureTLS(transport); err != nil {
+			return nil, err
 		}
+	}
 
-		cert, err := tls.X509KeyPair([]byte(certConfig.Cert), []byte(certConfig.Key))
+	if h.config.Proxy != nil {
+		selector, err := newProxySelector(h.config.Proxy, h.getLogger())
 		if err != nil {
-			return transport, fmt.Errorf("failed to load X509 key pair: %w", err)
+			return nil, fmt.Errorf("invalid proxy config: %w", err)
 		}
+		transport.Proxy = selector.proxyFunc
+	}
 
-		certificates = append(certificates, cert)
+	if h.hasDialSettings() {
+		h.configureDial(transport)
 	}
 
-	tlsConfig := &tls.Config{
-		RootCAs:            caCertPool,
-		Certificates:       certificates,
-		InsecureSkipVerify: h.config.InsecureSkipVerify,
+	if h.config.Transport != nil {
+		applyTransportConfig(transport, h.config.Transport)
 	}
 
-	transport = &http.Transport{
-		TLSClientConfig: tlsConfig,
-		IdleConnTimeout: defaultIdleConnTimeout,
+	if verify != nil && transport.DialTLSContext == nil && transport.DialTLS == nil {
+		installTLSDialer(transport, verify)
+	}
+
+	h.configurePoolTracking(transport)
+
+	return transport, nil
+}
+
+// configureDial installs the DialContext hook, the per-host dial overrides and
+// the DNS resolver.
+// Without a DialContext hook, connections are made with the transport's own dialer.
+func (h *httpClientFactory) configureDial(transport *http.Transport) {
+	dial := h.config.DialContext
+	if dial == nil {
+		dial = transport.DialContext
+	}
+	if dial == nil {
+		dial = defaultDialContext()
+	}
+
+	var resolver *dnsResolver
+	if h.config.DNS != nil {
+		resolver = newDNSResolver(h.config.DNS)
+	}
+
+	// http.Client.Timeout does not reach the dial context, so bound the dial
+	// with it for the resolver to share between addresses.
+	timeout := h.config.Timeout
+	if timeout <= 0 {
+		timeout = defaultDialTimeout
+	}
+
+	transport.DialContext = newDialer(h.config.DialOverrides, dial, resolver, timeout).DialContext
+}
+
+// configurePoolTracking counts the connections dialed by the transport for PoolStats.
+// Installing the dial hook keeps HTTP/2 enabled when net/http would have used it.
+func (h *httpClientFactory) configurePoolTracking(transport *http.Transport) {
+	if attemptsHTTP2(transport) {
+		transport.ForceAttemptHTTP2 = true
+	}
+
+	dial := transport.DialContext
+	if dial == nil {
+		dial = defaultDialContext()
+	}
+
+	h.pool = newPoolTracker()
+	transport.DialContext = h.pool.trackDial(dial)
+}
+
+// configureTLS applies the certificate and TLS verification settings
+// on top of any TLS configuration already present on the transport.
+// The returned function, if any, verifies servers against the dialed host and
+// is installed with installTLSDialer once the dial and proxy settings are in place.
+func (h *httpClientFactory) configureTLS(transport *http.Transport) (hostVerifyFunc, error) {
+	tlsConfig := &tls.Config{}
+	if transport.TLSClientConfig != nil {
+		tlsConfig = transport.TLSClientConfig.Clone()
+	}
+
+	if len(h.config.Certificates) > 0 {
+		certificates := make([]tls.Certificate, 0, len(h.config.Certificates))
+		caCertPool := x509.NewCertPool()
+
+		for _, certConfig := range h.config.Certificates {
+			ok := caCertPool.AppendCertsFromPEM([]byte(certConfig.Cert))
+			if !ok {
+				return nil, fmt.Errorf("failed to append certificate to pool")
+			}
+
+			cert, err := tls.X509KeyPair([]byte(certConfig.Cert), []byte(certConfig.Key))
+			if err != nil {
+				return nil, fmt.Errorf("failed to load X509 key pair: %w", err)
+			}
+
+			certificates = append(certificates, cert)
+		}
+
+		tlsConfig.RootCAs = caCertPool
+		tlsConfig.Certificates = append(tlsConfig.Certificates, certificates...)
+	}
+
+	if h.config.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	verify, err := applyTLSConfig(tlsConfig, h.config.TLS, h.getLogger())
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS config: %w", err)
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	// With custom TLS settings net/http only sets up HTTP/2 when asked to, so
+	// offering h2 alone would leave the connection without a handler for it.
+	if h.config.TLS != nil && slices.Contains(h.config.TLS.NextProtos, "h2") {
+		transport.ForceAttemptHTTP2 = true
 	}
 
-	return transport, err
+	return verify, nil
 }
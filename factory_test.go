@@ -1,11 +1,15 @@
 package vecto
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHTTPClientFactory(t *testing.T) {
@@ -32,7 +36,13 @@ func TestHTTPClientFactory(t *testing.T) {
 
 		client, err := factory.make()
 		assert.NoError(t, err)
-		assert.Equal(t, customTransport, client.Transport)
+
+		transport, ok := client.Transport.(*http.Transport)
+		require.True(t, ok)
+		assert.NotSame(t, customTransport, transport)
+		assert.Equal(t, 100, transport.MaxIdleConns)
+		assert.Nil(t, customTransport.DialContext)
+		assert.NotNil(t, factory.pool)
 	})
 
 	t.Run("with certificates", func(t *testing.T) {
@@ -63,6 +73,105 @@ func TestHTTPClientFactory(t *testing.T) {
 	})
 }
 
+func TestCustomRoundTripper(t *testing.T) {
+	t.Run("uses arbitrary round tripper", func(t *testing.T) {
+		var calls int32
+		v, err := New(Config{
+			BaseURL: "http://example.test",
+			HTTPTransport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				atomic.AddInt32(&calls, 1)
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     make(http.Header),
+					Body:       http.NoBody,
+					Request:    req,
+				}, nil
+			}),
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/ping", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("rejects transport settings for a custom round tripper", func(t *testing.T) {
+		roundTripper := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("unreachable")
+		})
+
+		configs := map[string]Config{
+			"InsecureSkipVerify": {InsecureSkipVerify: true},
+			"TLS":                {TLS: &TLSConfig{ServerName: "example.test"}},
+			"Proxy":              {Proxy: &ProxyConfig{URL: "http://proxy.test:8080"}},
+			"DialContext":        {DialContext: defaultDialContext()},
+			"DNS":                {DNS: &DNSConfig{}},
+			"Transport":          {Transport: &TransportConfig{}},
+		}
+
+		for name, config := range configs {
+			config.HTTPTransport = roundTripper
+			_, err := New(config)
+			require.Error(t, err, name)
+			assert.Contains(t, err.Error(), name+" cannot be applied to a custom round tripper", name)
+		}
+	})
+
+	t.Run("applies TLS settings to a clone of the supplied transport", func(t *testing.T) {
+		customTransport := &http.Transport{MaxIdleConns: 7}
+
+		factory := newHTTPClientFactory(Config{
+			HTTPTransport:      customTransport,
+			InsecureSkipVerify: true,
+		})
+
+		client, err := factory.make()
+		require.NoError(t, err)
+
+		transport, ok := client.Transport.(*http.Transport)
+		require.True(t, ok)
+		assert.NotSame(t, customTransport, transport)
+		assert.Equal(t, 7, transport.MaxIdleConns)
+		require.NotNil(t, transport.TLSClientConfig)
+		assert.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+		if customTransport.TLSClientConfig != nil {
+			assert.False(t, customTransport.TLSClientConfig.InsecureSkipVerify)
+		}
+	})
+
+	t.Run("middleware chain runs outermost first on every attempt", func(t *testing.T) {
+		srv := newHTTPTestServer()
+		defer srv.Close()
+
+		var order []string
+		tag := func(name string) RoundTripperMiddleware {
+			return func(next http.RoundTripper) http.RoundTripper {
+				return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					order = append(order, name)
+					return next.RoundTrip(req)
+				})
+			}
+		}
+
+		v, err := New(Config{
+			BaseURL:             srv.URL,
+			TransportMiddleware: []RoundTripperMiddleware{tag("outer"), tag("inner")},
+			Retry: &RetryConfig{
+				MaxAttempts: 2,
+				WaitTime:    time.Millisecond,
+				Backoff:     FixedBackoff,
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/test/status/500", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Equal(t, []string{"outer", "inner", "outer", "inner"}, order)
+	})
+}
+
 func TestGetTransportConfig(t *testing.T) {
 	t.Run("no certificates", func(t *testing.T) {
 		factory := newHTTPClientFactory(Config{})
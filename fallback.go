@@ -0,0 +1,75 @@
+package vecto
+
+import (
+	"context"
+)
+
+// FallbackFunc produces a substitute response when a request cannot be completed,
+// either because the circuit breaker rejected it or because it failed after all
+// retry attempts. The err argument is the failure that triggered the fallback
+// (a *CircuitBreakerError when the circuit is open). A retryable status is only
+// offered once it was retried, so with a single attempt it is returned as-is.
+//
+// Returning a nil response or a non-nil error keeps the original failure. When the
+// retries ran out on a retryable status, that is the last response, returned
+// without an error as if no fallback was configured.
+// Responses returned by a fallback are marked with IsFallback.
+//
+// Example:
+//
+//	Fallback: func(ctx context.Context, req *vecto.Request, err error) (*vecto.Response, error) {
+//	    if cached, ok := cache.Get(req.FullUrl()); ok {
+//	        return &vecto.Response{StatusCode: http.StatusOK, Data: cached}, nil
+//	    }
+//	    return nil, err
+//	}
+type FallbackFunc func(ctx context.Context, req *Request, err error) (*Response, error)
+
+func (v *Vecto) getFallback(options *RequestOptions) FallbackFunc {
+	if options != nil && options.Fallback != nil {
+		return options.Fallback
+	}
+
+	return v.config.Fallback
+}
+
+// executeFallback invokes the fallback for a failed request.
+// It returns false when no usable fallback response was produced.
+func (h *requestHandler) executeFallback(
+	ctx context.Context,
+	req *Request,
+	fallback FallbackFunc,
+	cause error,
+) (*Response, bool) {
+	if fallback == nil {
+		return nil, false
+	}
+
+	res, err := fallback(ctx, req, cause)
+	if err != nil || res == nil {
+		if err != nil && !h.vecto.logger.IsNoop() {
+			h.vecto.logger.Warn(ctx, "fallback failed", h.vecto.requestLogFields(ctx, req, map[string]interface{}{
+				"cause": cause.Error(),
+				"error": err.Error(),
+			}))
+		}
+		return nil, false
+	}
+
+	res.fallback = true
+	if res.request == nil {
+		res.request = req
+	}
+	res.success = h.vecto.config.ValidateStatus(res)
+
+	addSpanEvent(ctx, "fallback", map[string]any{"error.type": errorType(cause)})
+
+	if !h.vecto.logger.IsNoop() {
+		h.vecto.logger.Warn(ctx, "serving fallback response", h.vecto.requestLogFields(ctx, req, map[string]interface{}{
+			"status_code": res.StatusCode,
+			"cause":       cause.Error(),
+		}))
+	}
+
+	return res, true
+}
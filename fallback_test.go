@@ -0,0 +1,259 @@
+package vecto
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallback(t *testing.T) {
+	srv := newHTTPTestServer()
+	defer srv.Close()
+
+	t.Run("serves fallback when circuit is open", func(t *testing.T) {
+		collector := &mockMetricsCollector{}
+		cbConfig := DefaultCircuitBreakerConfig()
+		cbConfig.FailureThreshold = 1
+		cbConfig.Timeout = time.Minute
+
+		var fallbackErr error
+		v, err := New(Config{
+			BaseURL:          srv.URL,
+			MetricsCollector: collector,
+			CircuitBreaker:   &cbConfig,
+			Fallback: func(ctx context.Context, req *Request, err error) (*Response, error) {
+				fallbackErr = err
+				return &Response{StatusCode: http.StatusOK, Data: []byte(`{"cached":true}`)}, nil
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/test/status/500", nil)
+		require.NoError(t, err)
+		assert.False(t, res.IsFallback())
+
+		res, err = v.Get(context.Background(), "/test/status/200", nil)
+		require.NoError(t, err)
+		require.NotNil(t, res)
+		assert.True(t, res.IsFallback())
+		assert.True(t, res.Success())
+		assert.Equal(t, `{"cached":true}`, res.String())
+
+		var cbErr *CircuitBreakerError
+		assert.True(t, errors.As(fallbackErr, &cbErr))
+
+		require.Len(t, collector.requests, 2)
+		assert.False(t, collector.requests[0].Fallback)
+		assert.True(t, collector.requests[1].Fallback)
+		assert.Error(t, collector.requests[1].Error)
+	})
+
+	t.Run("serves fallback when retries are exhausted", func(t *testing.T) {
+		calls := 0
+		v, err := New(Config{
+			BaseURL: srv.URL,
+			Retry: &RetryConfig{
+				MaxAttempts: 2,
+				WaitTime:    time.Millisecond,
+				Backoff:     FixedBackoff,
+			},
+			Fallback: func(ctx context.Context, req *Request, err error) (*Response, error) {
+				calls++
+				return &Response{StatusCode: http.StatusNoContent}, nil
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/test/status/503", nil)
+		require.NoError(t, err)
+		assert.True(t, res.IsFallback())
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("returns the last response when retries are exhausted without fallback", func(t *testing.T) {
+		for _, maxAttempts := range []int{1, 3} {
+			v, err := New(Config{
+				BaseURL: srv.URL,
+				Retry: &RetryConfig{
+					MaxAttempts: maxAttempts,
+					WaitTime:    time.Millisecond,
+					Backoff:     FixedBackoff,
+				},
+			})
+			require.NoError(t, err)
+
+			res, err := v.Get(context.Background(), "/test/status/503", nil)
+			require.NoError(t, err, "max attempts %d", maxAttempts)
+			require.NotNil(t, res)
+			assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+			assert.False(t, res.Success())
+			assert.False(t, res.IsFallback())
+		}
+	})
+
+	t.Run("returns the last response when fallback declines exhausted retries", func(t *testing.T) {
+		v, err := New(Config{
+			BaseURL: srv.URL,
+			Retry: &RetryConfig{
+				MaxAttempts: 2,
+				WaitTime:    time.Millisecond,
+				Backoff:     FixedBackoff,
+			},
+			Fallback: func(ctx context.Context, req *Request, err error) (*Response, error) {
+				return nil, err
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/test/status/503", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.False(t, res.IsFallback())
+	})
+
+	t.Run("does not serve fallback without a retry", func(t *testing.T) {
+		calls := 0
+		v, err := New(Config{
+			BaseURL: srv.URL,
+			Retry:   &RetryConfig{MaxAttempts: 1},
+			Fallback: func(ctx context.Context, req *Request, err error) (*Response, error) {
+				calls++
+				return &Response{StatusCode: http.StatusNoContent}, nil
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/test/status/503", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.False(t, res.IsFallback())
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("request option overrides config fallback", func(t *testing.T) {
+		v, err := New(Config{
+			BaseURL: "http://127.0.0.1:1",
+			Fallback: func(ctx context.Context, req *Request, err error) (*Response, error) {
+				return &Response{StatusCode: http.StatusOK, Data: []byte("config")}, nil
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/unreachable", &RequestOptions{
+			Fallback: func(ctx context.Context, req *Request, err error) (*Response, error) {
+				return &Response{StatusCode: http.StatusOK, Data: []byte("request")}, nil
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "request", res.String())
+		assert.True(t, res.IsFallback())
+	})
+
+	t.Run("keeps original error when fallback declines", func(t *testing.T) {
+		logger := &mockLogger{}
+		v, err := New(Config{
+			BaseURL: "http://127.0.0.1:1",
+			Logger:  logger,
+			Fallback: func(ctx context.Context, req *Request, err error) (*Response, error) {
+				return nil, errors.New("no cached response")
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/unreachable", nil)
+		assert.Nil(t, res)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "http request failed")
+
+		found := false
+		for _, call := range logger.warnCalls {
+			if call.msg == "fallback failed" {
+				found = true
+			}
+		}
+		assert.True(t, found)
+	})
+}
+
+func TestFallbackCompletion(t *testing.T) {
+	srv := newHTTPTestServer()
+	defer srv.Close()
+
+	cbConfig := DefaultCircuitBreakerConfig()
+	cbConfig.FailureThreshold = 1
+	cbConfig.Timeout = time.Minute
+
+	tests := []struct {
+		name    string
+		config  Config
+		path    string
+		prepare func(t *testing.T, v *Vecto)
+	}{
+		{
+			name:   "request error",
+			config: Config{BaseURL: "http://127.0.0.1:1"},
+			path:   "/unreachable",
+		},
+		{
+			name:   "open circuit",
+			config: Config{BaseURL: srv.URL, CircuitBreaker: &cbConfig},
+			path:   "/test/status/200",
+			prepare: func(t *testing.T, v *Vecto) {
+				_, err := v.Get(context.Background(), "/test/status/500", nil)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "exhausted retries",
+			config: Config{
+				BaseURL: srv.URL,
+				Retry:   &RetryConfig{MaxAttempts: 2, WaitTime: time.Millisecond, Backoff: FixedBackoff},
+			},
+			path: "/test/status/503",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var debug strings.Builder
+			config := tt.config
+			config.DebugMode = true
+			config.Debug = &DebugConfig{Writer: &debug}
+			config.Fallback = func(ctx context.Context, req *Request, err error) (*Response, error) {
+				return &Response{StatusCode: http.StatusOK, Data: []byte("cached")}, nil
+			}
+
+			v, err := New(config)
+			require.NoError(t, err)
+			if tt.prepare != nil {
+				tt.prepare(t, v)
+			}
+			debug.Reset()
+
+			events := make(chan RequestCompletedEvent, 1)
+			v.Intercept(func(ctx context.Context, req *Request, next Next) (*Response, error) {
+				req.OnCompleted(events)
+				return next(ctx, req)
+			})
+
+			res, err := v.Get(context.Background(), tt.path, nil)
+			require.NoError(t, err)
+			require.True(t, res.IsFallback())
+
+			select {
+			case event := <-events:
+				assert.True(t, event.Response().IsFallback())
+			default:
+				t.Fatal("expected a completion event for the fallback response")
+			}
+			assert.Contains(t, debug.String(), "cached")
+		})
+	}
+}
@@ -0,0 +1,414 @@
+package vecto
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// FaultConfig configures a FaultInjector.
+type FaultConfig struct {
+	// Rules are evaluated in order for every attempt. The first rule that
+	// matches the attempt and wins its probability roll is applied, so an
+	// attempt gets at most one fault.
+	Rules []FaultRule
+
+	// Seed seeds the random source of the probability rolls, so a sequence
+	// of requests gets the same faults on every run.
+	// Default: 0 (seeded from the current time)
+	Seed int64
+}
+
+// FaultRule injects a fault into a share of the attempts. A rule applies
+// its latency, if any, then at most one of Reset, StatusCode, TruncateAfter
+// or DripInterval.
+type FaultRule struct {
+	// Name identifies the fault in RequestMetrics.InjectedFaults.
+	// Default: the kind of fault, e.g. "reset" or "status_503"
+	Name string
+
+	// Hosts restricts the rule to these hosts, as path.Match patterns
+	// such as "*.example.com". Empty matches every host.
+	// Default: nil
+	Hosts []string
+
+	// Routes restricts the rule to these routes, as path.Match patterns
+	// such as "/users/*". The route of the request is used when it has one,
+	// otherwise its path. Empty matches every route.
+	// Default: nil
+	Routes []string
+
+	// Methods restricts the rule to these methods. Empty matches every method.
+	// Default: nil
+	Methods []string
+
+	// Probability is the share of matching attempts the fault is injected
+	// into, from 0 to 1.
+	// Default: 0 (never)
+	Probability float64
+
+	// Latency delays the attempt before it is sent.
+	// Default: 0
+	Latency time.Duration
+
+	// Reset fails the attempt with a connection reset instead of sending it.
+	// Default: false
+	Reset bool
+
+	// StatusCode answers the attempt with this status instead of sending it.
+	// Default: 0 (the attempt is sent)
+	StatusCode int
+
+	// RetryAfter sets the Retry-After header of the StatusCode response.
+	// Default: 0 (no header)
+	RetryAfter time.Duration
+
+	// TruncateAfter cuts the response body after this many bytes, failing
+	// its read with io.ErrUnexpectedEOF as a dropped connection would. Bodies
+	// no longer than that are delivered complete. Such failures are not
+	// retried by DefaultRetryCondition.
+	// Default: 0 (the body is complete)
+	TruncateAfter int
+
+	// DripInterval delivers the response body DripBytes at a time, waiting
+	// this long before each chunk.
+	// Default: 0 (the body is delivered as received)
+	DripInterval time.Duration
+
+	// DripBytes is the size of each chunk of a dripped body.
+	// Default: 1
+	DripBytes int
+}
+
+func (r *FaultRule) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	switch {
+	case r.Reset:
+		return "reset"
+	case r.StatusCode != 0:
+		return "status_" + strconv.Itoa(r.StatusCode)
+	case r.TruncateAfter > 0:
+		return "truncate"
+	case r.DripInterval > 0:
+		return "drip"
+	default:
+		return "latency"
+	}
+}
+
+func validateFaultRule(rule *FaultRule) error {
+	if rule.Probability < 0 || rule.Probability > 1 {
+		return fmt.Errorf("probability must be between 0 and 1")
+	}
+
+	if rule.Latency < 0 || rule.RetryAfter < 0 || rule.DripInterval < 0 {
+		return fmt.Errorf("durations must not be negative")
+	}
+
+	if rule.StatusCode != 0 && (rule.StatusCode < 100 || rule.StatusCode > 599) {
+		return fmt.Errorf("invalid status code: %d", rule.StatusCode)
+	}
+
+	if rule.TruncateAfter < 0 || rule.DripBytes < 0 {
+		return fmt.Errorf("byte counts must not be negative")
+	}
+
+	faults := 0
+	for _, set := range []bool{rule.Reset, rule.StatusCode != 0, rule.TruncateAfter > 0, rule.DripInterval > 0} {
+		if set {
+			faults++
+		}
+	}
+	if faults > 1 {
+		return fmt.Errorf("only one of reset, status code, truncation and drip can be set")
+	}
+	if faults == 0 && rule.Latency == 0 {
+		return fmt.Errorf("no fault set")
+	}
+
+	for _, pattern := range append(append([]string(nil), rule.Hosts...), rule.Routes...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// FaultInjector injects faults into the attempts of a client to exercise
+// its resilience settings: added latency, connection resets, error
+// responses, truncated bodies and slow bodies. It is installed as
+// TransportMiddleware, so every attempt is affected independently: retries
+// and the circuit breaker react to the faults as they would to real ones,
+// metrics include them, and RequestMetrics.InjectedFaults lists them.
+//
+// Example:
+//
+//	injector, err := vecto.NewFaultInjector(vecto.FaultConfig{
+//	    Seed: 42,
+//	    Rules: []vecto.FaultRule{
+//	        {Routes: []string{"/orders/*"}, Probability: 0.1, StatusCode: 503, RetryAfter: time.Second},
+//	        {Probability: 0.05, Latency: 2 * time.Second},
+//	    },
+//	})
+//	client, _ := vecto.New(vecto.Config{
+//	    TransportMiddleware: []vecto.RoundTripperMiddleware{injector.Middleware()},
+//	})
+type FaultInjector struct {
+	rules    []FaultRule
+	disabled atomic.Bool
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewFaultInjector returns a FaultInjector with the given config.
+func NewFaultInjector(config FaultConfig) (*FaultInjector, error) {
+	rules := append([]FaultRule(nil), config.Rules...)
+	for i := range rules {
+		if err := validateFaultRule(&rules[i]); err != nil {
+			return nil, fmt.Errorf("invalid fault rule %d: %w", i, err)
+		}
+	}
+
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &FaultInjector{
+		rules: rules,
+		rand:  rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+// SetEnabled turns fault injection on or off. An injector starts enabled.
+func (f *FaultInjector) SetEnabled(enabled bool) {
+	f.disabled.Store(!enabled)
+}
+
+// Enabled reports whether faults are injected.
+func (f *FaultInjector) Enabled() bool {
+	return !f.disabled.Load()
+}
+
+// Middleware returns the TransportMiddleware injecting the faults.
+func (f *FaultInjector) Middleware() RoundTripperMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			rule := f.pick(req)
+			if rule == nil {
+				return next.RoundTrip(req)
+			}
+
+			requestStatsFromContext(req.Context()).addFault(rule.name())
+			return f.inject(next, req, rule)
+		})
+	}
+}
+
+// pick returns the rule to apply to req, if any.
+func (f *FaultInjector) pick(req *http.Request) *FaultRule {
+	if !f.Enabled() {
+		return nil
+	}
+
+	route, ok := RouteFromContext(req.Context())
+	if !ok {
+		route = req.URL.Path
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.rules {
+		rule := &f.rules[i]
+		if !rule.matches(req, route) {
+			continue
+		}
+		// Rolling only for matching rules keeps the faults of a host or
+		// route independent of the traffic to others.
+		if f.rand.Float64() < rule.Probability {
+			return rule
+		}
+	}
+
+	return nil
+}
+
+func (r *FaultRule) matches(req *http.Request, route string) bool {
+	if len(r.Methods) > 0 && !containsFold(r.Methods, req.Method) {
+		return false
+	}
+
+	if len(r.Hosts) > 0 && !matchAny(r.Hosts, req.URL.Hostname()) && !matchAny(r.Hosts, req.URL.Host) {
+		return false
+	}
+
+	if len(r.Routes) > 0 && !matchAny(r.Routes, route) {
+		return false
+	}
+
+	return true
+}
+
+func (f *FaultInjector) inject(next http.RoundTripper, req *http.Request, rule *FaultRule) (*http.Response, error) {
+	ctx := req.Context()
+
+	if rule.Latency > 0 {
+		if err := sleepContext(ctx, rule.Latency); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case rule.Reset:
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	case rule.StatusCode != 0:
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return faultResponse(req, rule), nil
+	}
+
+	res, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case rule.TruncateAfter > 0:
+		res.Body = &truncatedBody{body: res.Body, remaining: rule.TruncateAfter}
+	case rule.DripInterval > 0:
+		chunk := rule.DripBytes
+		if chunk == 0 {
+			chunk = 1
+		}
+		res.Body = &dripBody{ctx: ctx, body: res.Body, interval: rule.DripInterval, chunk: chunk}
+	}
+
+	return res, nil
+}
+
+func faultResponse(req *http.Request, rule *FaultRule) *http.Response {
+	body := []byte(fmt.Sprintf("injected fault: %s", http.StatusText(rule.StatusCode)))
+
+	header := http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}}
+	if rule.RetryAfter > 0 {
+		seconds := int((rule.RetryAfter + time.Second - 1) / time.Second)
+		header.Set("Retry-After", strconv.Itoa(seconds))
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rule.StatusCode, http.StatusText(rule.StatusCode)),
+		StatusCode:    rule.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// truncatedBody fails with io.ErrUnexpectedEOF after remaining bytes, unless
+// the body ends there.
+type truncatedBody struct {
+	body      io.ReadCloser
+	remaining int
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		var next [1]byte
+		n, err := b.body.Read(next[:])
+		if n > 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+
+	if len(p) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err := b.body.Read(p)
+	b.remaining -= n
+	return n, err
+}
+
+func (b *truncatedBody) Close() error {
+	return b.body.Close()
+}
+
+// dripBody delivers a body chunk bytes at a time, waiting interval before
+// each chunk.
+type dripBody struct {
+	ctx      context.Context
+	body     io.ReadCloser
+	interval time.Duration
+	chunk    int
+}
+
+func (b *dripBody) Read(p []byte) (int, error) {
+	if err := sleepContext(b.ctx, b.interval); err != nil {
+		return 0, err
+	}
+
+	if len(p) > b.chunk {
+		p = p[:b.chunk]
+	}
+	return b.body.Read(p)
+}
+
+func (b *dripBody) Close() error {
+	return b.body.Close()
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package vecto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFaultClient(t *testing.T, baseURL string, config FaultConfig, clientConfig Config) (*Vecto, *FaultInjector) {
+	t.Helper()
+
+	injector, err := NewFaultInjector(config)
+	require.NoError(t, err)
+
+	clientConfig.BaseURL = baseURL
+	clientConfig.TransportMiddleware = []RoundTripperMiddleware{injector.Middleware()}
+	v, err := New(clientConfig)
+	require.NoError(t, err)
+
+	return v, injector
+}
+
+func TestFaultInjectorStatus(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	collector := &mockMetricsCollector{}
+	v, injector := newFaultClient(t, srv.URL, FaultConfig{
+		Rules: []FaultRule{{
+			Routes:      []string{"/orders/*"},
+			Probability: 1,
+			StatusCode:  http.StatusTooManyRequests,
+			RetryAfter:  1500 * time.Millisecond,
+		}},
+	}, Config{MetricsCollector: collector})
+
+	res, err := v.Get(context.Background(), "/orders/{id}", &RequestOptions{PathParams: map[string]string{"id": "1"}})
+	require.NoError(t, err)
+	assert.False(t, res.Success())
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, "2", res.Header("Retry-After"))
+	assert.Equal(t, int32(0), calls.Load(), "the server is not called")
+
+	_, err = v.Get(context.Background(), "/users/1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+
+	injector.SetEnabled(false)
+	assert.False(t, injector.Enabled())
+	_, err = v.Get(context.Background(), "/orders/{id}", &RequestOptions{PathParams: map[string]string{"id": "1"}})
+	require.NoError(t, err)
+
+	require.Len(t, collector.requests, 3)
+	assert.Equal(t, []string{"status_429"}, collector.requests[0].InjectedFaults)
+	assert.Equal(t, "/orders/{id}", collector.requests[0].Route)
+	assert.Nil(t, collector.requests[1].InjectedFaults)
+	assert.Nil(t, collector.requests[2].InjectedFaults)
+}
+
+func TestFaultInjectorRetries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		rule      FaultRule
+		fault     string
+		condition RetryConditionFunc
+	}{
+		{name: "reset", rule: FaultRule{Reset: true}, fault: "reset"},
+		{name: "truncate", rule: FaultRule{TruncateAfter: 10}, fault: "truncate", condition: func(res *Response, err error) bool {
+			return errors.Is(err, io.ErrUnexpectedEOF) || DefaultRetryCondition(res, err)
+		}},
+		{name: "status", rule: FaultRule{Name: "unavailable", StatusCode: http.StatusServiceUnavailable}, fault: "unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &mockMetricsCollector{}
+			tt.rule.Probability = 1
+			var injector *FaultInjector
+			v, injector := newFaultClient(t, srv.URL, FaultConfig{Rules: []FaultRule{tt.rule}}, Config{
+				MetricsCollector: collector,
+				Retry: &RetryConfig{
+					MaxAttempts:    3,
+					WaitTime:       time.Millisecond,
+					RetryCondition: tt.condition,
+					OnRetry: func(attempt int, err error) {
+						if attempt == 2 {
+							injector.SetEnabled(false)
+						}
+					},
+				},
+			})
+
+			res, err := v.Get(context.Background(), "/", nil)
+			require.NoError(t, err)
+			assert.Len(t, res.Data, 100)
+
+			require.Len(t, collector.requests, 1)
+			metrics := collector.requests[0]
+			assert.Equal(t, 3, metrics.Attempts)
+			assert.Equal(t, []string{tt.fault, tt.fault}, metrics.InjectedFaults)
+		})
+	}
+}
+
+func TestFaultInjectorTruncate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer srv.Close()
+
+	t.Run("delivers a body of exactly the limit", func(t *testing.T) {
+		v, _ := newFaultClient(t, srv.URL, FaultConfig{
+			Rules: []FaultRule{{Probability: 1, TruncateAfter: 100}},
+		}, Config{})
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Len(t, res.Data, 100)
+	})
+
+	t.Run("fails a longer body without retrying it", func(t *testing.T) {
+		collector := &mockMetricsCollector{}
+		v, _ := newFaultClient(t, srv.URL, FaultConfig{
+			Rules: []FaultRule{{Probability: 1, TruncateAfter: 99}},
+		}, Config{
+			MetricsCollector: collector,
+			Retry:            &RetryConfig{MaxAttempts: 3, WaitTime: time.Millisecond},
+		})
+
+		_, err := v.Get(context.Background(), "/", nil)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+		require.Len(t, collector.requests, 1)
+		assert.Equal(t, 1, collector.requests[0].Attempts)
+	})
+}
+
+func TestFaultInjectorCircuitBreaker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	cbConfig := DefaultCircuitBreakerConfig()
+	cbConfig.FailureThreshold = 2
+	v, _ := newFaultClient(t, srv.URL, FaultConfig{
+		Rules: []FaultRule{{Probability: 1, Reset: true}},
+	}, Config{CircuitBreaker: &cbConfig})
+
+	for i := 0; i < 2; i++ {
+		_, err := v.Get(context.Background(), "/", nil)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, syscall.ECONNRESET), err.Error())
+	}
+
+	_, err := v.Get(context.Background(), "/", nil)
+	var cbErr *CircuitBreakerError
+	assert.True(t, errors.As(err, &cbErr), "the breaker opens on injected faults: %v", err)
+}
+
+func TestFaultInjectorLatencyAndDrip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("abcd"))
+	}))
+	defer srv.Close()
+
+	v, _ := newFaultClient(t, srv.URL, FaultConfig{
+		Rules: []FaultRule{
+			{Hosts: []string{"127.0.0.1"}, Routes: []string{"/slow"}, Probability: 1, Latency: time.Second},
+			{Methods: []string{http.MethodGet}, Routes: []string{"/drip"}, Probability: 1, DripInterval: 10 * time.Millisecond, DripBytes: 2},
+		},
+	}, Config{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := v.Get(ctx, "/slow", nil)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+
+	start = time.Now()
+	res, err := v.Get(context.Background(), "/drip", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "abcd", string(res.Data))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestFaultInjectorSeed(t *testing.T) {
+	rolls := func() []bool {
+		injector, err := NewFaultInjector(FaultConfig{
+			Seed:  42,
+			Rules: []FaultRule{{Probability: 0.5, Latency: time.Millisecond}},
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "http://api.test/", nil)
+		result := make([]bool, 20)
+		for i := range result {
+			result[i] = injector.pick(req) != nil
+		}
+		return result
+	}
+
+	first := rolls()
+	assert.Equal(t, first, rolls())
+	assert.Contains(t, first, true)
+	assert.Contains(t, first, false)
+}
+
+func TestNewFaultInjectorValidation(t *testing.T) {
+	invalid := []FaultRule{
+		{Probability: 2, Reset: true},
+		{Probability: 1},
+		{Probability: 1, Reset: true, StatusCode: 500},
+		{Probability: 1, StatusCode: 42},
+		{Probability: 1, Latency: -time.Second},
+		{Probability: 1, Reset: true, Routes: []string{"["}},
+	}
+
+	for _, rule := range invalid {
+		_, err := NewFaultInjector(FaultConfig{Rules: []FaultRule{rule}})
+		assert.Error(t, err, "%+v", rule)
+	}
+}
@@ -0,0 +1,568 @@
+package vecto
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const defaultHARMaxBodySize = 1024 * 1024
+
+// HARConfig configures a HARRecorder.
+type HARConfig struct {
+	// Path is the file the archive is written to by Flush and Close, and
+	// every FlushInterval if set. When empty the archive is only written by
+	// WriteTo and WriteFile.
+	// Default: ""
+	Path string
+
+	// FlushInterval writes the archive to Path periodically when entries
+	// were recorded since the last write, so traffic is kept even if the
+	// process exits without calling Close. A failed periodic write is
+	// retried at the next interval.
+	// Default: 0 (the archive is written by Flush and Close only)
+	FlushInterval time.Duration
+
+	// MaxBodySize is the number of bytes of each body recorded before it is
+	// truncated. A negative value records bodies in full.
+	// Default: 1 MiB
+	MaxBodySize int
+
+	// MaxEntries caps the entries kept in memory. Once reached, the oldest
+	// entry is dropped for every new one, so a long-running recorder stays
+	// bounded. Zero keeps every entry.
+	// Default: 0
+	MaxEntries int
+}
+
+// HARRecorder records the traffic of a client in HTTP Archive (HAR) 1.2
+// format, which browser devtools can open. Every attempt is an entry, so a
+// retried request appears once per attempt with its "_attempt" number and
+// the "_requestId" shared by all its attempts.
+//
+// Headers, URLs and bodies are redacted with the RedactionConfig of the
+// client. Timings come from the TraceInfo of each attempt, so they are only
+// broken down when Config.EnableTrace is set; otherwise the whole attempt is
+// reported as wait time.
+//
+// Entries are kept in memory; recording one never touches the disk. Close
+// the recorder to write the archive to HARConfig.Path and stop the periodic
+// writes.
+//
+// Example:
+//
+//	recorder := vecto.NewHARRecorder(vecto.HARConfig{Path: "traffic.har"})
+//	defer recorder.Close()
+//	client, _ := vecto.New(vecto.Config{HAR: recorder, EnableTrace: true})
+type HARRecorder struct {
+	config HARConfig
+
+	mu      sync.Mutex
+	entries []harEntry
+	dirty   bool
+
+	// writeMu serializes the writes to HARConfig.Path.
+	writeMu   sync.Mutex
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewHARRecorder returns a HARRecorder with the given config.
+func NewHARRecorder(config HARConfig) *HARRecorder {
+	r := &HARRecorder{config: config}
+
+	if config.Path != "" && config.FlushInterval > 0 {
+		r.stop = make(chan struct{})
+		r.done = make(chan struct{})
+		go r.flushPeriodically()
+	}
+
+	return r
+}
+
+// Len returns the number of entries recorded.
+func (r *HARRecorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.currentUnsafe())
+}
+
+// Reset drops every entry recorded.
+func (r *HARRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+	r.dirty = true
+}
+
+// WriteTo writes the archive as JSON to w.
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	data, err := marshalHAR(r.snapshot())
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// WriteFile writes the archive to the file at path, replacing it.
+func (r *HARRecorder) WriteFile(path string) error {
+	data, err := marshalHAR(r.snapshot())
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Flush writes the archive to HARConfig.Path, if set.
+func (r *HARRecorder) Flush() error {
+	return r.flush(false)
+}
+
+// Close stops the periodic writes and writes the archive to
+// HARConfig.Path, if set. Entries recorded after Close are kept in memory.
+func (r *HARRecorder) Close() error {
+	r.closeOnce.Do(func() {
+		if r.stop != nil {
+			close(r.stop)
+			<-r.done
+		}
+	})
+	return r.flush(false)
+}
+
+func (r *HARRecorder) flushPeriodically() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			_ = r.flush(true)
+		}
+	}
+}
+
+// flush writes the archive to HARConfig.Path. With onlyDirty, it is only
+// written if entries changed since the last write.
+func (r *HARRecorder) flush(onlyDirty bool) error {
+	if r.config.Path == "" {
+		return nil
+	}
+
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	r.mu.Lock()
+	if onlyDirty && !r.dirty {
+		r.mu.Unlock()
+		return nil
+	}
+	entries := append([]harEntry(nil), r.currentUnsafe()...)
+	r.dirty = false
+	r.mu.Unlock()
+
+	data, err := marshalHAR(entries)
+	if err == nil {
+		err = writeFileAtomic(r.config.Path, data)
+	}
+	if err != nil {
+		r.mu.Lock()
+		r.dirty = true
+		r.mu.Unlock()
+		return fmt.Errorf("failed to write HAR file: %w", err)
+	}
+
+	return nil
+}
+
+// snapshot returns a copy of the entries kept.
+func (r *HARRecorder) snapshot() []harEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]harEntry(nil), r.currentUnsafe()...)
+}
+
+// currentUnsafe returns the entries kept. entries may hold up to twice
+// MaxEntries so dropping the oldest ones is amortized.
+func (r *HARRecorder) currentUnsafe() []harEntry {
+	if limit := r.config.MaxEntries; limit > 0 && len(r.entries) > limit {
+		return r.entries[len(r.entries)-limit:]
+	}
+	return r.entries
+}
+
+func marshalHAR(entries []harEntry) ([]byte, error) {
+	if entries == nil {
+		entries = []harEntry{}
+	}
+
+	doc := harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "vecto", Version: "1.0"},
+		Entries: entries,
+	}}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// writeFileAtomic writes data to a temporary file renamed over path, so
+// readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// record adds the entry of one attempt.
+func (r *HARRecorder) record(ctx context.Context, req *Request, res *Response, attemptErr error, start time.Time, duration time.Duration, attempt int) {
+	entry := r.newEntry(ctx, req, res, attemptErr, start, duration, attempt)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, entry)
+	if limit := r.config.MaxEntries; limit > 0 && len(r.entries) >= 2*limit {
+		r.entries = append(r.entries[:0], r.entries[len(r.entries)-limit:]...)
+	}
+	r.dirty = true
+}
+
+func (r *HARRecorder) maxBodySize() int {
+	if r.config.MaxBodySize == 0 {
+		return defaultHARMaxBodySize
+	}
+	return r.config.MaxBodySize
+}
+
+func (r *HARRecorder) newEntry(ctx context.Context, req *Request, res *Response, attemptErr error, start time.Time, duration time.Duration, attempt int) harEntry {
+	rd := req.redaction()
+
+	var rawReq *http.Request
+	var trace *TraceInfo
+	if res != nil {
+		rawReq = res.RawRequest
+		trace = res.TraceInfo
+	}
+
+	requestHeaders := http.Header{}
+	if rawReq != nil {
+		requestHeaders = rawReq.Header
+	} else {
+		for key, value := range req.Headers() {
+			requestHeaders.Set(key, value)
+		}
+	}
+
+	fullURL := rd.url(req.FullUrl())
+	requestBody := rd.body(req.encodedBody())
+
+	entry := harEntry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Request: harRequest{
+			Method:      req.Method(),
+			URL:         fullURL,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(requestHeaders, rd),
+			QueryString: harQueryString(fullURL),
+			HeadersSize: -1,
+			BodySize:    len(requestBody),
+		},
+		Response: harResponse{
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			Content:     harContent{MimeType: "x-unknown"},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Cache:   struct{}{},
+		Timings: harTimingsFrom(trace, duration),
+		Attempt: attempt,
+	}
+	entry.RequestID, _ = RequestIDFromContext(ctx)
+	entry.Time = entry.Timings.total()
+
+	if rawReq != nil && rawReq.Proto != "" {
+		entry.Request.HTTPVersion = rawReq.Proto
+	}
+
+	if len(requestBody) > 0 {
+		text, comment := r.truncate(requestBody)
+		entry.Request.PostData = &harPostData{
+			MimeType: requestHeaders.Get("Content-Type"),
+			Text:     text,
+			Comment:  comment,
+		}
+	}
+
+	if trace != nil {
+		if host, _, err := net.SplitHostPort(trace.RemoteAddr); err == nil {
+			entry.ServerIPAddress = host
+		}
+	}
+
+	if attemptErr != nil {
+		entry.Error = strings.ReplaceAll(attemptErr.Error(), req.FullUrl(), fullURL)
+		entry.Response.HTTPVersion = entry.Request.HTTPVersion
+		return entry
+	}
+
+	if res == nil {
+		return entry
+	}
+
+	entry.Response.Status = res.StatusCode
+	entry.Response.StatusText = http.StatusText(res.StatusCode)
+	entry.Response.HTTPVersion = res.Protocol
+	if entry.Response.HTTPVersion == "" {
+		entry.Response.HTTPVersion = entry.Request.HTTPVersion
+	}
+	entry.Response.BodySize = len(res.Data)
+	entry.Response.Content = r.content(rd.body(res.Data), res.Header("Content-Type"))
+
+	if res.RawResponse != nil {
+		entry.Response.Headers = harHeaders(res.RawResponse.Header, rd)
+		entry.Response.RedirectURL = rd.url(res.RawResponse.Header.Get("Location"))
+	}
+
+	return entry
+}
+
+// content returns the content of a body, base64 encoded if it is not UTF-8.
+func (r *HARRecorder) content(body []byte, mimeType string) harContent {
+	content := harContent{Size: len(body), MimeType: mimeType}
+	if content.MimeType == "" {
+		content.MimeType = "x-unknown"
+	}
+
+	if len(body) == 0 {
+		return content
+	}
+
+	content.Text, content.Comment = r.truncate(body)
+	if !utf8.ValidString(content.Text) {
+		content.Text = base64.StdEncoding.EncodeToString([]byte(content.Text))
+		content.Encoding = "base64"
+	}
+
+	return content
+}
+
+// truncate returns body cut to HARConfig.MaxBodySize, with a comment if it was cut.
+// UTF-8 bodies are cut at a rune boundary so the text stays valid.
+func (r *HARRecorder) truncate(body []byte) (string, string) {
+	limit := r.maxBodySize()
+	if limit < 0 || len(body) <= limit {
+		return string(body), ""
+	}
+
+	cut := limit
+	if utf8.Valid(body) {
+		for cut > 0 && !utf8.RuneStart(body[cut]) {
+			cut--
+		}
+	}
+
+	return string(body[:cut]), fmt.Sprintf("truncated to %d of %d bytes", cut, len(body))
+}
+
+// harTimingsFrom converts a TraceInfo to HAR timings. Without a trace the
+// whole attempt is reported as wait time. Blocked, send, wait and receive
+// are measured between the trace events, so the timings add up to the
+// duration of the attempt: wait runs from the request being written to the
+// first response byte, as HAR 1.2 defines it.
+func harTimingsFrom(trace *TraceInfo, duration time.Duration) harTimings {
+	if trace == nil {
+		return harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: milliseconds(duration)}
+	}
+
+	gotConn, _ := trace.eventAt("GotConn")
+	wroteRequest, wrote := trace.eventAt("WroteRequest")
+	firstByte, gotFirstByte := trace.eventAt("GotFirstResponseByte")
+
+	timings := harTimings{
+		Blocked: -1,
+		DNS:     -1,
+		Connect: -1,
+		SSL:     -1,
+		Send:    milliseconds(trace.RequestWrite),
+		Receive: milliseconds(trace.ContentTransfer),
+	}
+
+	if wrote && gotFirstByte && firstByte >= wroteRequest {
+		timings.Wait = milliseconds(firstByte - wroteRequest)
+	}
+
+	setup := time.Duration(0)
+	if !trace.ConnReused {
+		timings.DNS = milliseconds(trace.DNSLookup)
+		// HAR counts the TLS handshake in both connect and ssl.
+		timings.Connect = milliseconds(trace.TCPConnection + trace.TLSHandshake)
+		if trace.TLSHandshake > 0 {
+			timings.SSL = milliseconds(trace.TLSHandshake)
+		}
+		setup = trace.DNSLookup + trace.TCPConnection + trace.TLSHandshake
+	}
+
+	if blocked := gotConn - setup; blocked > 0 {
+		timings.Blocked = milliseconds(blocked)
+	}
+
+	return timings
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func harHeaders(headers http.Header, rd *redactor) []harNameValue {
+	result := make([]harNameValue, 0, len(headers))
+	for _, name := range sortedKeys(headers) {
+		for _, value := range headers[name] {
+			result = append(result, harNameValue{Name: name, Value: rd.header(name, value)})
+		}
+	}
+	return result
+}
+
+// harQueryString lists the query parameters of an already redacted URL.
+func harQueryString(rawURL string) []harNameValue {
+	result := []harNameValue{}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return result
+	}
+
+	query := u.Query()
+	for _, name := range sortedKeys(query) {
+		for _, value := range query[name] {
+			result = append(result, harNameValue{Name: name, Value: value})
+		}
+	}
+
+	return result
+}
+
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	RequestID       string      `json:"_requestId,omitempty"`
+	Attempt         int         `json:"_attempt,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// total is the entry time: the sum of the timings that apply, with ssl
+// already counted in connect.
+func (t harTimings) total() float64 {
+	total := t.Send + t.Wait + t.Receive
+	for _, timing := range []float64{t.Blocked, t.DNS, t.Connect} {
+		if timing > 0 {
+			total += timing
+		}
+	}
+	return total
+}
@@ -0,0 +1,319 @@
+package vecto
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type harTestEntry struct {
+	Time    float64 `json:"time"`
+	Request struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *harPostData   `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int            `json:"status"`
+		Headers []harNameValue `json:"headers"`
+		Content harContent     `json:"content"`
+	} `json:"response"`
+	Timings   harTimings `json:"timings"`
+	RequestID string     `json:"_requestId"`
+	Attempt   int        `json:"_attempt"`
+	Error     string     `json:"_error"`
+}
+
+func readHAR(t *testing.T, recorder *HARRecorder) []harTestEntry {
+	t.Helper()
+
+	var buf bytes.Buffer
+	_, err := recorder.WriteTo(&buf)
+	require.NoError(t, err)
+
+	return decodeHAR(t, buf.Bytes())
+}
+
+func decodeHAR(t *testing.T, data []byte) []harTestEntry {
+	t.Helper()
+
+	var doc struct {
+		Log struct {
+			Version string         `json:"version"`
+			Entries []harTestEntry `json:"entries"`
+		} `json:"log"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	require.Equal(t, "1.2", doc.Log.Version)
+
+	return doc.Log.Entries
+}
+
+func harHeader(headers []harNameValue, name string) string {
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func TestHARRecorder(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"token":"t","id":1}`))
+	}))
+	defer srv.Close()
+
+	recorder := NewHARRecorder(HARConfig{})
+	v, err := New(Config{
+		BaseURL:     srv.URL,
+		EnableTrace: true,
+		HAR:         recorder,
+		Retry:       &RetryConfig{MaxAttempts: 2, WaitTime: time.Millisecond},
+		Redaction: &RedactionConfig{
+			QueryParams: []string{"api_key"},
+			BodyFields:  []string{"password", "token"},
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	_, err = v.Post(ctx, "/login?api_key=secret&page=1", &RequestOptions{
+		Headers: map[string]string{"Authorization": "Bearer abc"},
+		Data:    map[string]string{"user": "u", "password": "p"},
+	})
+	require.NoError(t, err)
+
+	entries := readHAR(t, recorder)
+	require.Len(t, entries, 2)
+	assert.Equal(t, 2, recorder.Len())
+
+	for i, entry := range entries {
+		assert.Equal(t, i+1, entry.Attempt)
+		assert.Equal(t, "req-1", entry.RequestID)
+		assert.Equal(t, http.MethodPost, entry.Request.Method)
+		assert.Equal(t, srv.URL+"/login?api_key=[REDACTED]&page=1", entry.Request.URL)
+		assert.Contains(t, entry.Request.QueryString, harNameValue{Name: "api_key", Value: "[REDACTED]"})
+		assert.Equal(t, "[REDACTED]", harHeader(entry.Request.Headers, "Authorization"))
+		assert.Equal(t, "[REDACTED]", harHeader(entry.Response.Headers, "Set-Cookie"))
+		require.NotNil(t, entry.Request.PostData)
+		assert.JSONEq(t, `{"user":"u","password":"[REDACTED]"}`, entry.Request.PostData.Text)
+		assert.GreaterOrEqual(t, entry.Timings.Wait, float64(0))
+		assert.Greater(t, entry.Time, float64(0))
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, entries[0].Response.Status)
+	assert.Equal(t, http.StatusOK, entries[1].Response.Status)
+	assert.Equal(t, "application/json", entries[1].Response.Content.MimeType)
+	assert.JSONEq(t, `{"token":"[REDACTED]","id":1}`, entries[1].Response.Content.Text)
+	assert.GreaterOrEqual(t, entries[0].Timings.Connect, float64(0))
+	assert.Equal(t, float64(-1), entries[1].Timings.Connect, "second attempt reuses the connection")
+
+	recorder.Reset()
+	assert.Empty(t, readHAR(t, recorder))
+}
+
+func TestHARRecorderBodies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/runes" {
+			w.Write([]byte("x" + strings.Repeat("é", 10)))
+			return
+		}
+		if r.URL.Path == "/binary" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0xff, 0xfe, 0xfd})
+			return
+		}
+		w.Write([]byte(strings.Repeat("x", 20)))
+	}))
+	defer srv.Close()
+
+	recorder := NewHARRecorder(HARConfig{MaxBodySize: 10})
+	v, err := New(Config{BaseURL: srv.URL, HAR: recorder})
+	require.NoError(t, err)
+
+	_, err = v.Get(context.Background(), "/text", nil)
+	require.NoError(t, err)
+	_, err = v.Get(context.Background(), "/binary", nil)
+	require.NoError(t, err)
+	_, err = v.Get(context.Background(), "/runes", nil)
+	require.NoError(t, err)
+
+	entries := readHAR(t, recorder)
+	require.Len(t, entries, 3)
+
+	text := entries[0].Response.Content
+	assert.Equal(t, 20, text.Size)
+	assert.Equal(t, strings.Repeat("x", 10), text.Text)
+	assert.Equal(t, "truncated to 10 of 20 bytes", text.Comment)
+	assert.Equal(t, float64(-1), entries[0].Timings.DNS, "timings are not broken down without tracing")
+
+	binary := entries[1].Response.Content
+	assert.Equal(t, "base64", binary.Encoding)
+	assert.Equal(t, "//79", binary.Text)
+
+	runes := entries[2].Response.Content
+	assert.Empty(t, runes.Encoding)
+	assert.Equal(t, "x"+strings.Repeat("é", 4), runes.Text, "truncation keeps runes whole")
+	assert.Equal(t, "truncated to 9 of 21 bytes", runes.Comment)
+}
+
+func TestHARRecorderErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	recorder := NewHARRecorder(HARConfig{})
+	v, err := New(Config{
+		BaseURL:   srv.URL,
+		HAR:       recorder,
+		Redaction: &RedactionConfig{QueryParams: []string{"key"}},
+	})
+	require.NoError(t, err)
+
+	_, err = v.Get(context.Background(), "/?key=secret", nil)
+	require.Error(t, err)
+
+	entries := readHAR(t, recorder)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 0, entries[0].Response.Status)
+	assert.NotEmpty(t, entries[0].Error)
+	assert.NotContains(t, entries[0].Error, "secret")
+}
+
+func TestHARRecorderFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "traffic.har")
+	recorder := NewHARRecorder(HARConfig{Path: path, MaxEntries: 2})
+	v, err := New(Config{BaseURL: srv.URL, HAR: recorder})
+	require.NoError(t, err)
+
+	for _, p := range []string{"/a", "/b", "/c"} {
+		_, err = v.Get(context.Background(), p, nil)
+		require.NoError(t, err)
+	}
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "recording does not write the file")
+	assert.Equal(t, 2, recorder.Len())
+
+	require.NoError(t, recorder.Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	entries := decodeHAR(t, data)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "/b", entries[0].Response.Content.Text)
+	assert.Equal(t, "/c", entries[1].Response.Content.Text)
+
+	other := filepath.Join(t.TempDir(), "copy.har")
+	require.NoError(t, recorder.WriteFile(other))
+	copied, err := os.ReadFile(other)
+	require.NoError(t, err)
+	assert.Equal(t, data, copied)
+
+	assert.Error(t, NewHARRecorder(HARConfig{Path: filepath.Join(t.TempDir(), "missing", "x.har")}).Flush())
+	assert.NoError(t, NewHARRecorder(HARConfig{}).Close())
+}
+
+func TestHARRecorderFlushInterval(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "traffic.har")
+	recorder := NewHARRecorder(HARConfig{Path: path, FlushInterval: 5 * time.Millisecond})
+	defer recorder.Close()
+
+	v, err := New(Config{BaseURL: srv.URL, HAR: recorder})
+	require.NoError(t, err)
+
+	_, err = v.Get(context.Background(), "/", nil)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		data, err := os.ReadFile(path)
+		return err == nil && len(decodeHAR(t, data)) == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestHARRecorderMaxEntries(t *testing.T) {
+	recorder := NewHARRecorder(HARConfig{MaxEntries: 3})
+	req := &Request{method: http.MethodGet, url: "http://api.test/"}
+
+	for i := 0; i < 20; i++ {
+		recorder.record(context.Background(), req, nil, nil, time.Now(), time.Millisecond, i+1)
+		assert.LessOrEqual(t, len(recorder.entries), 6)
+	}
+
+	entries := readHAR(t, recorder)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []int{18, 19, 20}, []int{entries[0].Attempt, entries[1].Attempt, entries[2].Attempt})
+}
+
+func TestHARTimings(t *testing.T) {
+	ms := time.Millisecond
+	trace := &TraceInfo{
+		DNSLookup:        5 * ms,
+		TCPConnection:    10 * ms,
+		TLSHandshake:     20 * ms,
+		RequestWrite:     3 * ms,
+		ServerProcessing: 80 * ms,
+		ContentTransfer:  7 * ms,
+		Total:            90 * ms,
+		Events: []TraceEvent{
+			{Name: "GetConn", At: 0},
+			{Name: "GotConn", At: 37 * ms},
+			{Name: "WroteRequest", At: 40 * ms},
+			{Name: "GotFirstResponseByte", At: 83 * ms},
+		},
+	}
+
+	timings := harTimingsFrom(trace, trace.Total)
+	assert.Equal(t, float64(43), timings.Wait)
+	assert.Equal(t, float64(2), timings.Blocked)
+	assert.Equal(t, float64(30), timings.Connect)
+	assert.Equal(t, float64(20), timings.SSL)
+	assert.InDelta(t, milliseconds(trace.Total), timings.total(), 0.001)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	recorder := NewHARRecorder(HARConfig{})
+	v, err := New(Config{BaseURL: srv.URL, EnableTrace: true, HAR: recorder})
+	require.NoError(t, err)
+
+	start := time.Now()
+	res, err := v.Get(context.Background(), "/", nil)
+	elapsed := time.Since(start)
+	require.NoError(t, err)
+
+	entries := readHAR(t, recorder)
+	require.Len(t, entries, 1)
+	assert.InDelta(t, milliseconds(res.TraceInfo.Total), entries[0].Time, 1)
+	assert.LessOrEqual(t, entries[0].Time, milliseconds(elapsed))
+	assert.GreaterOrEqual(t, entries[0].Timings.Wait, float64(20))
+}
@@ -0,0 +1,127 @@
+package vecto
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	minHTTP2FrameSize = 16 << 10
+	maxHTTP2FrameSize = 16 << 20
+)
+
+// HTTP2Config tunes HTTP/2. Setting it makes the transport attempt HTTP/2
+// even when custom TLS or dial settings are configured.
+type HTTP2Config struct {
+	// Cleartext sends http:// requests as HTTP/2 over plain TCP, assuming the
+	// server supports it (h2c with prior knowledge). HTTP/1 is disabled, so
+	// https:// servers must negotiate HTTP/2 through ALPN.
+	// Default: false
+	Cleartext bool
+
+	// ReadIdleTimeout is how long a connection may go without receiving a frame
+	// before a health check ping is sent.
+	// Default: 0 (no health check)
+	ReadIdleTimeout time.Duration
+
+	// PingTimeout is how long to wait for the health check ping response
+	// before the connection is closed.
+	// Default: 15s
+	PingTimeout time.Duration
+
+	// WriteByteTimeout closes a connection when no data can be written to it
+	// for this long.
+	// Default: 0 (no timeout)
+	WriteByteTimeout time.Duration
+
+	// MaxHeaderListSize limits the size of the response headers, in bytes.
+	// It applies to HTTP/1 responses as well.
+	// Default: 0 (net/http allows 10MB)
+	MaxHeaderListSize int64
+
+	// MaxReadFrameSize is the largest frame accepted, between 16KiB and 16MiB.
+	// Default: 0 (16KiB)
+	MaxReadFrameSize int
+}
+
+func validateHTTP2Config(config *HTTP2Config) error {
+	if config == nil {
+		return nil
+	}
+
+	if config.ReadIdleTimeout < 0 {
+		return fmt.Errorf("HTTP/2 read idle timeout cannot be negative")
+	}
+
+	if config.PingTimeout < 0 {
+		return fmt.Errorf("HTTP/2 ping timeout cannot be negative")
+	}
+
+	if config.WriteByteTimeout < 0 {
+		return fmt.Errorf("HTTP/2 write byte timeout cannot be negative")
+	}
+
+	if config.MaxHeaderListSize < 0 {
+		return fmt.Errorf("HTTP/2 max header list size cannot be negative")
+	}
+
+	if config.MaxReadFrameSize != 0 &&
+		(config.MaxReadFrameSize < minHTTP2FrameSize || config.MaxReadFrameSize > maxHTTP2FrameSize) {
+		return fmt.Errorf("HTTP/2 max read frame size must be between %d and %d, got: %d",
+			minHTTP2FrameSize, maxHTTP2FrameSize, config.MaxReadFrameSize)
+	}
+
+	return nil
+}
+
+func applyHTTP2Config(transport *http.Transport, config *HTTP2Config) {
+	http2Config := &http.HTTP2Config{}
+	if transport.HTTP2 != nil {
+		copied := *transport.HTTP2
+		http2Config = &copied
+	}
+
+	if config.ReadIdleTimeout > 0 {
+		http2Config.SendPingTimeout = config.ReadIdleTimeout
+	}
+	if config.PingTimeout > 0 {
+		http2Config.PingTimeout = config.PingTimeout
+	}
+	if config.WriteByteTimeout > 0 {
+		http2Config.WriteByteTimeout = config.WriteByteTimeout
+	}
+	if config.MaxReadFrameSize > 0 {
+		http2Config.MaxReadFrameSize = config.MaxReadFrameSize
+	}
+
+	transport.HTTP2 = http2Config
+	transport.ForceAttemptHTTP2 = true
+
+	if config.MaxHeaderListSize > 0 {
+		transport.MaxResponseHeaderBytes = config.MaxHeaderListSize
+	}
+
+	if config.Cleartext {
+		protocols := &http.Protocols{}
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		transport.Protocols = protocols
+	}
+}
+
+// attemptsHTTP2 reports whether net/http would enable HTTP/2 on transport as
+// it is now configured. It runs before the pool tracking dial hook, so TLS and
+// dial settings applied earlier by vecto count as if set by the caller.
+func attemptsHTTP2(transport *http.Transport) bool {
+	if transport.Protocols != nil || transport.TLSNextProto != nil {
+		return false
+	}
+
+	return transport.ForceAttemptHTTP2 ||
+		(transport.TLSClientConfig == nil &&
+			transport.Dial == nil &&
+			transport.DialContext == nil &&
+			transport.DialTLS == nil &&
+			transport.DialTLSContext == nil)
+}
@@ -0,0 +1,130 @@
+package vecto
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateHTTP2Config(t *testing.T) {
+	assert.NoError(t, validateHTTP2Config(nil))
+	assert.NoError(t, validateHTTP2Config(&HTTP2Config{Cleartext: true, MaxReadFrameSize: 1 << 20}))
+
+	err := validateHTTP2Config(&HTTP2Config{MaxReadFrameSize: 1024})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP/2 max read frame size must be between")
+
+	_, err = New(Config{Transport: &TransportConfig{HTTP2: &HTTP2Config{PingTimeout: -time.Second}}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid transport config: HTTP/2 ping timeout cannot be negative")
+}
+
+func TestHTTP2(t *testing.T) {
+	protoHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	t.Run("sends h2c with prior knowledge", func(t *testing.T) {
+		srv := httptest.NewUnstartedServer(protoHandler)
+		srv.Config.Protocols = &http.Protocols{}
+		srv.Config.Protocols.SetHTTP1(true)
+		srv.Config.Protocols.SetUnencryptedHTTP2(true)
+		srv.Start()
+		defer srv.Close()
+
+		v, err := New(Config{
+			BaseURL:     srv.URL,
+			EnableTrace: true,
+			Transport: &TransportConfig{
+				HTTP2: &HTTP2Config{
+					Cleartext:       true,
+					ReadIdleTimeout: 30 * time.Second,
+					PingTimeout:     5 * time.Second,
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/2.0", res.String())
+		assert.Equal(t, "HTTP/2.0", res.Protocol)
+		require.NotNil(t, res.TraceInfo)
+		assert.Equal(t, "HTTP/2.0", res.TraceInfo.Protocol)
+		assert.True(t, strings.Contains(res.TraceInfo.String(), "Protocol:          HTTP/2.0"))
+	})
+
+	t.Run("uses HTTP/1.1 without h2c", func(t *testing.T) {
+		srv := httptest.NewServer(protoHandler)
+		defer srv.Close()
+
+		v, err := New(Config{BaseURL: srv.URL})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/1.1", res.Protocol)
+	})
+
+	t.Run("negotiates HTTP/2 over TLS with custom TLS settings", func(t *testing.T) {
+		srv := httptest.NewUnstartedServer(protoHandler)
+		srv.EnableHTTP2 = true
+		srv.StartTLS()
+		defer srv.Close()
+
+		v, err := New(Config{
+			BaseURL: srv.URL,
+			TLS: &TLSConfig{
+				RootCAs:    []CABundle{{PEM: serverCertificatePEM(srv)}},
+				ServerName: "example.com",
+			},
+			Transport: &TransportConfig{HTTP2: &HTTP2Config{MaxHeaderListSize: 1 << 20}},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/2.0", res.Protocol)
+	})
+
+	t.Run("applies tuning to the transport", func(t *testing.T) {
+		factory := newHTTPClientFactory(Config{
+			Transport: &TransportConfig{
+				HTTP2: &HTTP2Config{
+					ReadIdleTimeout:   30 * time.Second,
+					PingTimeout:       5 * time.Second,
+					WriteByteTimeout:  10 * time.Second,
+					MaxHeaderListSize: 64 << 10,
+					MaxReadFrameSize:  1 << 20,
+				},
+			},
+		})
+
+		rt, err := factory.getTransport()
+		require.NoError(t, err)
+
+		transport := rt.(*http.Transport)
+		require.NotNil(t, transport.HTTP2)
+		assert.Equal(t, 30*time.Second, transport.HTTP2.SendPingTimeout)
+		assert.Equal(t, 5*time.Second, transport.HTTP2.PingTimeout)
+		assert.Equal(t, 10*time.Second, transport.HTTP2.WriteByteTimeout)
+		assert.Equal(t, 1<<20, transport.HTTP2.MaxReadFrameSize)
+		assert.Equal(t, int64(64<<10), transport.MaxResponseHeaderBytes)
+		assert.True(t, transport.ForceAttemptHTTP2)
+		assert.Nil(t, transport.Protocols)
+	})
+
+	t.Run("keeps HTTP/2 enabled on the default transport", func(t *testing.T) {
+		factory := newHTTPClientFactory(Config{})
+
+		rt, err := factory.getTransport()
+		require.NoError(t, err)
+		assert.True(t, rt.(*http.Transport).ForceAttemptHTTP2)
+	})
+}
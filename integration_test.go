@@ -127,13 +127,17 @@ func TestIntegrationEndToEnd(t *testing.T) {
 			return req, nil
 		})
 
-		_, err = v.Get(context.Background(), "/test/status/500", nil)
+		res, err := v.Get(context.Background(), "/test/status/500", nil)
 
 		mu.Lock()
 		finalAttempts := attempts
 		mu.Unlock()
 
-		assert.NotNil(t, err)
+		assert.Nil(t, err)
+		if assert.NotNil(t, res) {
+			assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+			assert.False(t, res.Success())
+		}
 		assert.True(t, finalAttempts >= 1, fmt.Sprintf("expected multiple attempts, got %d", finalAttempts))
 	})
 
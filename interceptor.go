@@ -0,0 +1,92 @@
+package vecto
+
+import (
+	"context"
+	"fmt"
+)
+
+// Next sends a request through the rest of the interceptor chain.
+type Next func(ctx context.Context, req *Request) (*Response, error)
+
+// Interceptor wraps the sending of a request. It may change the request
+// before calling next, inspect or replace the response and error it
+// returns, time the call, or return a response without calling next at all.
+// next may also be called more than once.
+//
+// Example:
+//
+//	client.Intercept(func(ctx context.Context, req *vecto.Request, next vecto.Next) (*vecto.Response, error) {
+//	    start := time.Now()
+//	    res, err := next(ctx, req)
+//	    log.Printf("%s %s took %s (err: %v)", req.Method(), req.FullUrl(), time.Since(start), err)
+//	    return res, err
+//	})
+type Interceptor func(ctx context.Context, req *Request, next Next) (*Response, error)
+
+// RequestInterceptor adapts a request middleware to an Interceptor that
+// runs mw before calling next.
+func RequestInterceptor(mw RequestMiddlewareFunc) Interceptor {
+	return func(ctx context.Context, req *Request, next Next) (*Response, error) {
+		resultReq, err := mw(ctx, req)
+		if err != nil {
+			return nil, &middlewareError{stage: "request", err: err}
+		}
+		return next(ctx, resultReq)
+	}
+}
+
+// ResponseInterceptor adapts a response middleware to an Interceptor that
+// runs mw on the response returned by next. Like response middleware, mw
+// is not called when next fails.
+func ResponseInterceptor(mw ResponseMiddlewareFunc) Interceptor {
+	return func(ctx context.Context, req *Request, next Next) (*Response, error) {
+		res, err := next(ctx, req)
+		if err != nil || res == nil {
+			return res, err
+		}
+
+		resultRes, err := mw(ctx, res)
+		if err != nil {
+			return nil, &middlewareError{stage: "response", res: res, err: err}
+		}
+		return resultRes, nil
+	}
+}
+
+// middlewareError is the error of a request or response middleware run by
+// an adapter, so doRequest reports it as such.
+type middlewareError struct {
+	stage string
+	res   *Response
+	err   error
+}
+
+func (e *middlewareError) Error() string {
+	return fmt.Sprintf("%s middleware failed: %v", e.stage, e.err)
+}
+
+func (e *middlewareError) Unwrap() error {
+	return e.err
+}
+
+// chainInterceptors returns final wrapped by interceptors, the first one
+// being the outermost.
+func chainInterceptors(interceptors []Interceptor, final Next) Next {
+	next := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := interceptors[i], next
+		next = func(ctx context.Context, req *Request) (*Response, error) {
+			return interceptor(ctx, req, inner)
+		}
+	}
+	return next
+}
+
+// doAttempt sends a single attempt of req through the attempt interceptors.
+func (v *Vecto) doAttempt(ctx context.Context, req *Request) (*Response, error) {
+	interceptors := v.middleware.getAttempt()
+	if len(interceptors) == 0 {
+		return v.sendAttempt(ctx, req)
+	}
+	return chainInterceptors(interceptors, v.sendAttempt)(ctx, req)
+}
@@ -0,0 +1,227 @@
+package vecto
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterceptOrder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	v, err := New(Config{BaseURL: srv.URL})
+	require.NoError(t, err)
+
+	var order []string
+	around := func(name string) Interceptor {
+		return func(ctx context.Context, req *Request, next Next) (*Response, error) {
+			order = append(order, name+" before")
+			res, err := next(ctx, req)
+			order = append(order, name+" after")
+			return res, err
+		}
+	}
+
+	v.UseResponse(func(ctx context.Context, res *Response) (*Response, error) {
+		order = append(order, "response-1")
+		return res, nil
+	})
+	v.Intercept(around("outer"))
+	v.UseRequest(func(ctx context.Context, req *Request) (*Request, error) {
+		order = append(order, "request")
+		return req, nil
+	})
+	v.Intercept(around("inner"))
+	v.InterceptAttempt(around("attempt"))
+	v.UseResponse(func(ctx context.Context, res *Response) (*Response, error) {
+		order = append(order, "response-2")
+		return res, nil
+	})
+
+	_, err = v.Get(context.Background(), "/", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"outer before",
+		"request",
+		"inner before",
+		"attempt before",
+		"attempt after",
+		"inner after",
+		"outer after",
+		"response-1",
+		"response-2",
+	}, order)
+}
+
+func TestInterceptShortCircuit(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	collector := &mockMetricsCollector{}
+	v, err := New(Config{BaseURL: srv.URL, MetricsCollector: collector})
+	require.NoError(t, err)
+
+	v.Intercept(func(ctx context.Context, req *Request, next Next) (*Response, error) {
+		return &Response{StatusCode: http.StatusOK, Data: []byte("cached")}, nil
+	})
+
+	events := make(chan RequestCompletedEvent, 1)
+	v.UseRequest(func(ctx context.Context, req *Request) (*Request, error) {
+		req.OnCompleted(events)
+		return req, nil
+	})
+
+	res, err := v.Get(context.Background(), "/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "cached", string(res.Data))
+	assert.True(t, res.Success())
+	assert.Equal(t, int32(0), calls.Load())
+	assert.Empty(t, events, "request middleware added after the interceptor do not run")
+
+	require.Len(t, collector.requests, 1)
+	assert.Equal(t, 0, collector.requests[0].Attempts)
+	assert.True(t, collector.requests[0].Success)
+}
+
+func TestInterceptErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	t.Run("sees transport errors", func(t *testing.T) {
+		v, err := New(Config{BaseURL: srv.URL})
+		require.NoError(t, err)
+
+		var seen error
+		v.Intercept(func(ctx context.Context, req *Request, next Next) (*Response, error) {
+			res, err := next(ctx, req)
+			seen = err
+			return res, err
+		})
+
+		_, err = v.Get(context.Background(), "/", nil)
+		require.Error(t, err)
+		assert.Error(t, seen)
+		assert.Contains(t, err.Error(), "http request failed")
+	})
+
+	t.Run("recovers transport errors", func(t *testing.T) {
+		v, err := New(Config{BaseURL: srv.URL})
+		require.NoError(t, err)
+
+		v.Intercept(func(ctx context.Context, req *Request, next Next) (*Response, error) {
+			if _, err := next(ctx, req); err != nil {
+				return &Response{StatusCode: http.StatusServiceUnavailable}, nil
+			}
+			return nil, errors.New("unreachable")
+		})
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.False(t, res.Success())
+	})
+
+	t.Run("middleware errors", func(t *testing.T) {
+		v, err := New(Config{BaseURL: srv.URL})
+		require.NoError(t, err)
+
+		errBoom := errors.New("boom")
+		v.UseRequest(func(ctx context.Context, req *Request) (*Request, error) {
+			return nil, errBoom
+		})
+
+		_, err = v.Get(context.Background(), "/", nil)
+		require.Error(t, err)
+		assert.Equal(t, "request middleware failed: boom", err.Error())
+		assert.ErrorIs(t, err, errBoom)
+	})
+
+	t.Run("no response", func(t *testing.T) {
+		v, err := New(Config{BaseURL: srv.URL})
+		require.NoError(t, err)
+
+		v.Intercept(func(ctx context.Context, req *Request, next Next) (*Response, error) {
+			return nil, nil
+		})
+
+		_, err = v.Get(context.Background(), "/", nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestInterceptRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	v, err := New(Config{
+		BaseURL: srv.URL,
+		Retry:   &RetryConfig{MaxAttempts: 3, WaitTime: time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	var callCount, attemptCount int
+	var statuses []int
+	v.Intercept(func(ctx context.Context, req *Request, next Next) (*Response, error) {
+		callCount++
+		return next(ctx, req)
+	})
+	v.InterceptAttempt(func(ctx context.Context, req *Request, next Next) (*Response, error) {
+		attemptCount++
+		res, err := next(ctx, req)
+		if res != nil {
+			statuses = append(statuses, res.StatusCode)
+		}
+		return res, err
+	})
+
+	res, err := v.Get(context.Background(), "/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 1, callCount)
+	assert.Equal(t, 3, attemptCount)
+	assert.Equal(t, []int{503, 503, 200}, statuses)
+}
+
+func TestInterceptCircuitBreaker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cbConfig := DefaultCircuitBreakerConfig()
+	cbConfig.FailureThreshold = 1
+	v, err := New(Config{BaseURL: srv.URL, CircuitBreaker: &cbConfig})
+	require.NoError(t, err)
+
+	v.Intercept(func(ctx context.Context, req *Request, next Next) (*Response, error) {
+		res, err := next(ctx, req)
+		if err != nil {
+			return nil, errors.Join(errors.New("wrapped"), err)
+		}
+		return res, nil
+	})
+
+	_, err = v.Get(context.Background(), "/", nil)
+	require.NoError(t, err)
+
+	_, err = v.Get(context.Background(), "/", nil)
+	var cbErr *CircuitBreakerError
+	assert.True(t, errors.As(err, &cbErr), "%v", err)
+}
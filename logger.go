@@ -2,8 +2,12 @@ package vecto
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 )
 
+// LogLevel is the severity of a log entry. Config.LogLevel sets the minimum
+// level passed to the Logger.
 type LogLevel int
 
 const (
@@ -32,3 +36,95 @@ func (n *noopLogger) IsNoop() bool
 func newNoopLogger() Logger {
 	return &noopLogger{}
 }
+
+// levelLogger drops the entries of a Logger below a minimum level.
+type levelLogger struct {
+	Logger
+	min LogLevel
+}
+
+// newLevelLogger returns logger filtered to min and above.
+func newLevelLogger(logger Logger, min LogLevel) Logger {
+	if min <= LogLevelDebug || logger.IsNoop() {
+		return logger
+	}
+	return &levelLogger{Logger: logger, min: min}
+}
+
+func (l *levelLogger) Debug(ctx context.Context, msg string, fields map[string]interface{}) {
+	if l.min <= LogLevelDebug {
+		l.Logger.Debug(ctx, msg, fields)
+	}
+}
+
+func (l *levelLogger) Info(ctx context.Context, msg string, fields map[string]interface{}) {
+	if l.min <= LogLevelInfo {
+		l.Logger.Info(ctx, msg, fields)
+	}
+}
+
+func (l *levelLogger) Warn(ctx context.Context, msg string, fields map[string]interface{}) {
+	if l.min <= LogLevelWarn {
+		l.Logger.Warn(ctx, msg, fields)
+	}
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying id, which is then logged
+// as the request_id of the requests made with it. Without one, vecto
+// generates a random request ID.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx. Inside a
+// request, such as in middleware, it is the ID logged for the request.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+func ensureRequestID(ctx context.Context) context.Context {
+	if _, ok := RequestIDFromContext(ctx); ok {
+		return ctx
+	}
+
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return ContextWithRequestID(ctx, hex.EncodeToString(b[:]))
+}
+
+// requestLogFields returns fields with the fields shared by the log entries
+// of a request added: request_id, method, url, route and breaker_key.
+// The entries of fields take precedence, and the URL is redacted in all of them.
+func (v *Vecto) requestLogFields(ctx context.Context, req *Request, fields map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(fields)+5)
+
+	if id, ok := RequestIDFromContext(ctx); ok {
+		result["request_id"] = id
+	}
+
+	if req != nil {
+		req.mu.RLock()
+		result["method"] = req.method
+		result["url"] = req.url
+		if req.route != "" {
+			result["route"] = req.route
+		}
+		if req.cbKeyCached {
+			result["breaker_key"] = req.cbKey
+		}
+		req.mu.RUnlock()
+	}
+
+	for k, value := range fields {
+		result[k] = value
+	}
+
+	if rawURL, ok := result["url"].(string); ok {
+		v.redactor.logFields(result, rawURL)
+	}
+
+	return result
+}
@@ -2,6 +2,10 @@ package vecto
 
 import (
 	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,17 +14,19 @@ func (v *Vecto) recordMetrics(ctx context.Context, req *Request, res *Response,
 		return
 	}
 
-	var normalizedURL, fullURL string
+	var normalizedURL, fullURL, route string
 	var method string
 	var requestSize int64
 	var statusCode int
 	var responseSize int64
 	var success bool
+	var fallback bool
 
 	if req != nil {
 		method = req.Method()
 		fullURL = req.FullUrl()
 		normalizedURL = v.normalizeURL(req)
+		route = req.Route()
 
 		if req.RawRequest() != nil && req.RawRequest().Body != nil {
 			if req.RawRequest().ContentLength > 0 {
@@ -29,15 +35,22 @@ func (v *Vecto) recordMetrics(ctx context.Context, req *Request, res *Response,
 		}
 	}
 
+	var protocol string
+	var trace *TraceInfo
+
 	if res != nil {
 		statusCode = res.StatusCode
 		responseSize = int64(len(res.Data))
 		success = res.success
+		fallback = res.fallback
+		protocol = res.Protocol
+		trace = res.TraceInfo
 	}
 
 	metrics := RequestMetrics{
 		Method:       method,
 		URL:          normalizedURL,
+		Route:        route,
 		FullURL:      fullURL,
 		Duration:     duration,
 		StatusCode:   statusCode,
@@ -45,8 +58,13 @@ func (v *Vecto) recordMetrics(ctx context.Context, req *Request, res *Response,
 		RequestSize:  requestSize,
 		ResponseSize: responseSize,
 		Success:      success,
+		Fallback:     fallback,
+		Protocol:     protocol,
+		Trace:        trace,
 	}
 
+	requestStatsFromContext(ctx).apply(&metrics)
+
 	v.config.MetricsCollector.RecordRequest(ctx, metrics)
 }
 
@@ -55,15 +73,17 @@ func (v *Vecto) recordMetricsWithFallback(ctx context.Context, method, url strin
 		return
 	}
 
-	var normalizedURL, fullURL string
+	var normalizedURL, fullURL, route string
 	var requestSize int64
 	var statusCode int
 	var responseSize int64
 	var success bool
+	var fallback bool
 
 	if req != nil {
 		fullURL = req.FullUrl()
 		normalizedURL = v.normalizeURL(req)
+		route = req.Route()
 		if req.RawRequest() != nil && req.RawRequest().Body != nil {
 			if req.RawRequest().ContentLength > 0 {
 				requestSize = req.RawRequest().ContentLength
@@ -78,11 +98,13 @@ func (v *Vecto) recordMetricsWithFallback(ctx context.Context, method, url strin
 		statusCode = res.StatusCode
 		responseSize = int64(len(res.Data))
 		success = res.success
+		fallback = res.fallback
 	}
 
 	metrics := RequestMetrics{
 		Method:       method,
 		URL:          normalizedURL,
+		Route:        route,
 		FullURL:      fullURL,
 		Duration:     duration,
 		StatusCode:   statusCode,
@@ -90,6 +112,7 @@ func (v *Vecto) recordMetricsWithFallback(ctx context.Context, method, url strin
 		RequestSize:  requestSize,
 		ResponseSize: responseSize,
 		Success:      success,
+		Fallback:     fallback,
 	}
 
 	v.config.MetricsCollector.RecordRequest(ctx, metrics)
@@ -114,3 +137,197 @@ func (v *Vecto) normalizeURL(req *Request) string {
 
 	return scheme + "://" + host + path
 }
+
+// trackInFlight reports the request as started to an InFlightRecorder and
+// returns the func reporting it as finished.
+func (v *Vecto) trackInFlight(ctx context.Context, req *Request) func() {
+	recorder, ok := v.config.MetricsCollector.(InFlightRecorder)
+	if !ok {
+		return func() {}
+	}
+
+	method, route := req.Method(), v.metricsRoute(req)
+	recorder.RequestStarted(ctx, method, route)
+
+	return func() {
+		recorder.RequestFinished(ctx, method, route)
+	}
+}
+
+func (v *Vecto) recordRetry(ctx context.Context, req *Request, attempt int) {
+	if recorder, ok := v.config.MetricsCollector.(RetryRecorder); ok {
+		recorder.RecordRetry(ctx, req.Method(), v.metricsRoute(req), attempt)
+	}
+}
+
+// circuitBreakerStateHook chains the recorder of the MetricsCollector, if
+// any, after the OnStateChange callback.
+func circuitBreakerStateHook(collector MetricsCollector, onStateChange func(from, to CircuitBreakerState, key string)) func(from, to CircuitBreakerState, key string) {
+	recorder, ok := collector.(CircuitBreakerStateRecorder)
+	if !ok {
+		return onStateChange
+	}
+
+	return func(from, to CircuitBreakerState, key string) {
+		if onStateChange != nil {
+			onStateChange(from, to, key)
+		}
+		recorder.RecordCircuitBreakerState(key, to)
+	}
+}
+
+// circuitBreakerCreateHook records new breakers as closed with the recorder of
+// the MetricsCollector, if any, so they are reported before their first state change.
+func circuitBreakerCreateHook(collector MetricsCollector) func(key string) {
+	recorder, ok := collector.(CircuitBreakerStateRecorder)
+	if !ok {
+		return nil
+	}
+
+	return func(key string) {
+		recorder.RecordCircuitBreakerState(key, StateClosed)
+	}
+}
+
+type requestStatsKey struct{}
+
+// requestStats collects the details of a request that are reported in its
+// RequestMetrics but not visible on the Response, such as failed attempts.
+type requestStats struct {
+	mu            sync.Mutex
+	attempts      []time.Duration
+	requestSize   int64
+	sentBody      bool
+	rateLimitWait time.Duration
+	cacheHit      bool
+	breakerState  string
+	faults        []string
+}
+
+func withRequestStats(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestStatsKey{}, &requestStats{})
+}
+
+func requestStatsFromContext(ctx context.Context) *requestStats {
+	stats, _ := ctx.Value(requestStatsKey{}).(*requestStats)
+	return stats
+}
+
+func (s *requestStats) addAttempt(duration time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = append(s.attempts, duration)
+}
+
+func (s *requestStats) attemptCount() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.attempts)
+}
+
+func (s *requestStats) setRequestSize(size int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requestSize = size
+	s.sentBody = true
+}
+
+func (s *requestStats) setBreakerState(state CircuitBreakerState) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.breakerState = state.String()
+}
+
+func (s *requestStats) apply(metrics *RequestMetrics) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metrics.Attempts = len(s.attempts)
+	metrics.AttemptDurations = append([]time.Duration(nil), s.attempts...)
+	metrics.RateLimitWait = s.rateLimitWait
+	metrics.CacheHit = s.cacheHit
+	metrics.CircuitBreakerState = s.breakerState
+	metrics.InjectedFaults = append([]string(nil), s.faults...)
+	if s.sentBody {
+		metrics.RequestSize = s.requestSize
+	}
+}
+
+// RecordRateLimitWait adds wait to the rate-limit wait reported in the
+// RequestMetrics of the request carrying ctx. Like RouteFromContext, it is
+// meant for rate limiters installed as TransportMiddleware.
+func RecordRateLimitWait(ctx context.Context, wait time.Duration) {
+	stats := requestStatsFromContext(ctx)
+	if stats == nil {
+		return
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.rateLimitWait += wait
+}
+
+// RecordCacheHit marks the request carrying ctx as served from a cache in its
+// RequestMetrics. It is meant for caches installed as TransportMiddleware.
+func RecordCacheHit(ctx context.Context) {
+	stats := requestStatsFromContext(ctx)
+	if stats == nil {
+		return
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.cacheHit = true
+}
+
+func (s *requestStats) addFault(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, name)
+}
+
+// countingBody counts the request body bytes read by the transport.
+type countingBody struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
+
+// countRequestBody wraps the body of httpReq so the bytes sent are reported
+// in the RequestMetrics. The returned func records the count.
+func countRequestBody(ctx context.Context, httpReq *http.Request) func() {
+	stats := requestStatsFromContext(ctx)
+	if stats == nil {
+		return func() {}
+	}
+
+	if httpReq.Body == nil || httpReq.Body == http.NoBody {
+		return func() { stats.setRequestSize(0) }
+	}
+
+	body := &countingBody{ReadCloser: httpReq.Body}
+	httpReq.Body = body
+
+	return func() { stats.setRequestSize(body.n.Load()) }
+}
@@ -2,9 +2,16 @@ package vecto
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockMetricsCollector struct {
@@ -304,3 +311,100 @@ func TestNormalizeURL(t *testing.T) {
 	})
 }
 
+func TestRecordMetrics_RequestDetails(t *testing.T) {
+	t.Run("records attempts, body size, protocol and trace", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(io.Discard, r.Body)
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer srv.Close()
+
+		collector := &mockMetricsCollector{}
+		v, err := New(Config{
+			BaseURL:          srv.URL,
+			MetricsCollector: collector,
+			EnableTrace:      true,
+			Retry:            &RetryConfig{MaxAttempts: 3, WaitTime: time.Millisecond},
+		})
+		require.NoError(t, err)
+
+		body := strings.Repeat("x", 1500)
+		_, err = v.Post(context.Background(), "/upload", &RequestOptions{
+			Data: body,
+			RequestTransform: func(req *Request) ([]byte, error) {
+				return []byte(req.Data().(string)), nil
+			},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, collector.requests, 1)
+		m := collector.requests[0]
+		assert.Equal(t, 3, m.Attempts)
+		assert.Len(t, m.AttemptDurations, 3)
+		assert.Equal(t, int64(len(body)), m.RequestSize)
+		assert.Equal(t, "HTTP/1.1", m.Protocol)
+		require.NotNil(t, m.Trace)
+		assert.Equal(t, 3, m.Trace.Attempt)
+		assert.Empty(t, m.CircuitBreakerState)
+		assert.False(t, m.CacheHit)
+	})
+
+	t.Run("records values reported by transport middleware", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+
+		limiter := func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				RecordRateLimitWait(req.Context(), 20*time.Millisecond)
+				RecordCacheHit(req.Context())
+				return next.RoundTrip(req)
+			})
+		}
+
+		collector := &mockMetricsCollector{}
+		cbConfig := DefaultCircuitBreakerConfig()
+		v, err := New(Config{
+			BaseURL:             srv.URL,
+			MetricsCollector:    collector,
+			TransportMiddleware: []RoundTripperMiddleware{limiter},
+			CircuitBreaker:      &cbConfig,
+		})
+		require.NoError(t, err)
+
+		_, err = v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+
+		require.Len(t, collector.requests, 1)
+		m := collector.requests[0]
+		assert.Equal(t, 1, m.Attempts)
+		assert.Equal(t, 20*time.Millisecond, m.RateLimitWait)
+		assert.True(t, m.CacheHit)
+		assert.Equal(t, "closed", m.CircuitBreakerState)
+		assert.Zero(t, m.RequestSize)
+		assert.Nil(t, m.Trace)
+	})
+
+	t.Run("records the state of a rejecting circuit breaker", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		collector := &mockMetricsCollector{}
+		cbConfig := DefaultCircuitBreakerConfig()
+		cbConfig.FailureThreshold = 1
+		v, err := New(Config{BaseURL: srv.URL, MetricsCollector: collector, CircuitBreaker: &cbConfig})
+		require.NoError(t, err)
+
+		_, _ = v.Get(context.Background(), "/", nil)
+		_, err = v.Get(context.Background(), "/", nil)
+		require.Error(t, err)
+
+		require.Len(t, collector.requests, 2)
+		assert.Equal(t, "open", collector.requests[1].CircuitBreakerState)
+		assert.Zero(t, collector.requests[1].Attempts)
+	})
+}
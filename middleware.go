@@ -2,59 +2,341 @@ package vecto
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"sync"
 )
 
 type RequestMiddlewareFunc func(ctx context.Context, req *Request) (resultReq *Request, err error)
 type ResponseMiddlewareFunc func(ctx context.Context, res *Response) (resultRes *Response, err error)
 
+// MiddlewareScope is what a middleware wraps.
+type MiddlewareScope int
+
+const (
+	// MiddlewareScopeCall wraps the whole logical call, like Intercept.
+	MiddlewareScopeCall MiddlewareScope = iota
+	// MiddlewareScopeAttempt wraps each attempt, like InterceptAttempt.
+	MiddlewareScopeAttempt
+)
+
+func (s MiddlewareScope) String() string {
+	switch s {
+	case MiddlewareScopeCall:
+		return "call"
+	case MiddlewareScopeAttempt:
+		return "attempt"
+	default:
+		return "unknown"
+	}
+}
+
+// MiddlewareOptions configures a middleware added with Use.
+type MiddlewareOptions struct {
+	// Name identifies the middleware for RemoveMiddleware, Before and After.
+	// Names are unique per client.
+	// Default: "" (the middleware cannot be removed or referenced)
+	Name string
+
+	// Scope is what the middleware wraps.
+	// Default: MiddlewareScopeCall
+	Scope MiddlewareScope
+
+	// Priority orders the middleware of a scope: higher priorities run
+	// first, as outer middleware. Middleware of equal priority run in the
+	// order they were added.
+	// Default: 0
+	Priority int
+
+	// Before places the middleware right before, so outside, the named
+	// middleware of the same scope, regardless of Priority.
+	// Default: ""
+	Before string
+
+	// After places the middleware right after, so inside, the named
+	// middleware of the same scope, regardless of Priority.
+	// Default: ""
+	After string
+}
+
+// MiddlewareInfo describes an installed middleware, as listed by
+// ListMiddleware.
+type MiddlewareInfo struct {
+	Name     string
+	Kind     string
+	Scope    MiddlewareScope
+	Priority int
+	Before   string
+	After    string
+}
+
+const (
+	middlewareKindInterceptor = "interceptor"
+	middlewareKindRequest     = "request"
+	middlewareKindResponse    = "response"
+)
+
+type middlewareEntry struct {
+	MiddlewareOptions
+	kind        string
+	seq         int
+	interceptor Interceptor
+}
+
+// anchor returns the name of the middleware the entry is placed next to.
+func (e *middlewareEntry) anchor() string {
+	if e.Before != "" {
+		return e.Before
+	}
+	return e.After
+}
+
 type middlewareCollection struct {
-	mu         sync.RWMutex
-	requestMW  []RequestMiddlewareFunc
-	responseMW []ResponseMiddlewareFunc
+	mu      sync.RWMutex
+	entries []*middlewareEntry
+	seq     int
+	call    []*middlewareEntry
+	attempt []*middlewareEntry
 }
 
 func newMiddlewareCollection() *middlewareCollection {
 	return &middlewareCollection{
-		requestMW:  make([]RequestMiddlewareFunc, 0, 4),
-		responseMW: make([]ResponseMiddlewareFunc, 0, 4),
+		entries: make([]*middlewareEntry, 0, 4),
 	}
 }
 
-func (c *middlewareCollection) addRequest(mw RequestMiddlewareFunc) {
+func (c *middlewareCollection) add(interceptor Interceptor, kind string, opts MiddlewareOptions) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.requestMW = append(c.requestMW, mw)
+
+	if opts.Before != "" && opts.After != "" {
+		return fmt.Errorf("middleware cannot be placed both before %q and after %q", opts.Before, opts.After)
+	}
+
+	if opts.Name != "" && c.find(opts.Name) != nil {
+		return fmt.Errorf("middleware %q is already registered", opts.Name)
+	}
+
+	if anchor := opts.Before + opts.After; anchor != "" {
+		entry := c.find(anchor)
+		if entry == nil {
+			return fmt.Errorf("middleware %q not found", anchor)
+		}
+		if entry.Scope != opts.Scope {
+			return fmt.Errorf("middleware %q is a %s middleware, not a %s one", anchor, entry.Scope, opts.Scope)
+		}
+	}
+
+	c.seq++
+	c.entries = append(c.entries, &middlewareEntry{
+		MiddlewareOptions: opts,
+		kind:              kind,
+		seq:               c.seq,
+		interceptor:       interceptor,
+	})
+	c.resolve()
+	return nil
+}
+
+func (c *middlewareCollection) addCall(interceptor Interceptor) {
+	_ = c.add(interceptor, middlewareKindInterceptor, MiddlewareOptions{})
+}
+
+func (c *middlewareCollection) addRequest(mw RequestMiddlewareFunc) {
+	_ = c.add(RequestInterceptor(mw), middlewareKindRequest, MiddlewareOptions{})
 }
 
 func (c *middlewareCollection) addResponse(mw ResponseMiddlewareFunc) {
+	_ = c.add(ResponseInterceptor(mw), middlewareKindResponse, MiddlewareOptions{})
+}
+
+func (c *middlewareCollection) addAttempt(interceptor Interceptor) {
+	_ = c.add(interceptor, middlewareKindInterceptor, MiddlewareOptions{Scope: MiddlewareScopeAttempt})
+}
+
+func (c *middlewareCollection) remove(name string) bool {
+	if name == "" {
+		return false
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.responseMW = append(c.responseMW, mw)
+
+	for i, entry := range c.entries {
+		if entry.Name == name {
+			c.entries = append(c.entries[:i], c.entries[i+1:]...)
+			c.resolve()
+			return true
+		}
+	}
+	return false
 }
 
-func (c *middlewareCollection) getRequest() []RequestMiddlewareFunc {
+func (c *middlewareCollection) list() []MiddlewareInfo {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	if len(c.requestMW) == 0 {
-		return nil
+	result := make([]MiddlewareInfo, 0, len(c.call)+len(c.attempt))
+	for _, entries := range [][]*middlewareEntry{c.call, c.attempt} {
+		for _, entry := range entries {
+			result = append(result, MiddlewareInfo{
+				Name:     entry.Name,
+				Kind:     entry.kind,
+				Scope:    entry.Scope,
+				Priority: entry.Priority,
+				Before:   entry.Before,
+				After:    entry.After,
+			})
+		}
 	}
-
-	result := make([]RequestMiddlewareFunc, len(c.requestMW))
-	copy(result, c.requestMW)
 	return result
 }
 
-func (c *middlewareCollection) getResponse() []ResponseMiddlewareFunc {
+func (c *middlewareCollection) find(name string) *middlewareEntry {
+	for _, entry := range c.entries {
+		if entry.Name == name {
+			return entry
+		}
+	}
+	return nil
+}
+
+// resolve orders the entries of each scope into the chains. The caller
+// must hold the write lock.
+func (c *middlewareCollection) resolve() {
+	var call, attempt []*middlewareEntry
+	for _, entry := range c.entries {
+		if entry.Scope == MiddlewareScopeAttempt {
+			attempt = append(attempt, entry)
+		} else {
+			call = append(call, entry)
+		}
+	}
+
+	c.call = orderMiddleware(call)
+	c.attempt = orderMiddleware(attempt)
+}
+
+// orderMiddleware sorts entries by priority, then places the ones with a
+// Before or After next to their anchor. An entry whose anchor was removed
+// falls back to its priority.
+func orderMiddleware(entries []*middlewareEntry) []*middlewareEntry {
+	names := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.Name != "" {
+			names[entry.Name] = true
+		}
+	}
+
+	var ordered, anchored []*middlewareEntry
+	for _, entry := range entries {
+		if names[entry.anchor()] {
+			anchored = append(anchored, entry)
+		} else {
+			ordered = append(ordered, entry)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return middlewareLess(ordered[i], ordered[j])
+	})
+
+	// Anchors may themselves be anchored, so place entries as their anchor
+	// gets placed.
+	for len(anchored) > 0 {
+		var pending []*middlewareEntry
+		for _, entry := range anchored {
+			i := indexOfMiddleware(ordered, entry.anchor())
+			if i < 0 {
+				pending = append(pending, entry)
+				continue
+			}
+			if entry.After != "" {
+				i++
+				for i < len(ordered) && ordered[i].After == entry.After {
+					i++
+				}
+			}
+			ordered = insertMiddleware(ordered, i, entry)
+		}
+
+		if len(pending) == len(anchored) {
+			// The entries left are anchored to each other, as an anchor was
+			// removed and added again next to them. Order them by priority.
+			for _, entry := range pending {
+				i := 0
+				for i < len(ordered) && !middlewareLess(entry, ordered[i]) {
+					i++
+				}
+				ordered = insertMiddleware(ordered, i, entry)
+			}
+			break
+		}
+		anchored = pending
+	}
+
+	return ordered
+}
+
+// middlewareLess reports whether a runs before b. Response middleware run
+// before, so wrap, the other middleware of their priority, in the order
+// they were added.
+func middlewareLess(a, b *middlewareEntry) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+
+	aResponse, bResponse := a.kind == middlewareKindResponse, b.kind == middlewareKindResponse
+	if aResponse != bResponse {
+		return aResponse
+	}
+	if aResponse {
+		return a.seq > b.seq
+	}
+	return a.seq < b.seq
+}
+
+func indexOfMiddleware(entries []*middlewareEntry, name string) int {
+	for i, entry := range entries {
+		if entry.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func insertMiddleware(entries []*middlewareEntry, i int, entry *middlewareEntry) []*middlewareEntry {
+	entries = append(entries, nil)
+	copy(entries[i+1:], entries[i:])
+	entries[i] = entry
+	return entries
+}
+
+func (c *middlewareCollection) getCall() []Interceptor {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return interceptorsOf(c.call)
+}
+
+func (c *middlewareCollection) getAttempt() []Interceptor {
+	if c == nil {
+		return nil
+	}
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	return interceptorsOf(c.attempt)
+}
 
-	if len(c.responseMW) == 0 {
+func interceptorsOf(entries []*middlewareEntry) []Interceptor {
+	if len(entries) == 0 {
 		return nil
 	}
 
-	result := make([]ResponseMiddlewareFunc, len(c.responseMW))
-	copy(result, c.responseMW)
+	result := make([]Interceptor, len(entries))
+	for i, entry := range entries {
+		result[i] = entry.interceptor
+	}
 	return result
 }
@@ -188,3 +188,134 @@ func TestMiddlewareConcurrentAccess(t *testing.T) {
 	_, err := vecto.Get(context.Background(), "/test/pets/1", nil)
 	assert.Nil(t, err)
 }
+
+func recordingInterceptor(order *[]string, name string) Interceptor {
+	return func(ctx context.Context, req *Request, next Next) (*Response, error) {
+		*order = append(*order, name)
+		return next(ctx, req)
+	}
+}
+
+func TestUseNamedMiddleware(t *testing.T) {
+	srv := newHTTPTestServer()
+	defer srv.Close()
+
+	vecto, _ := New(Config{BaseURL: srv.URL})
+
+	var order []string
+	vecto.Intercept(recordingInterceptor(&order, "anonymous"))
+	assert.NoError(t, vecto.Use(recordingInterceptor(&order, "logging"), MiddlewareOptions{Name: "logging"}))
+	assert.NoError(t, vecto.Use(recordingInterceptor(&order, "auth"), MiddlewareOptions{Name: "auth", Priority: 10}))
+	assert.NoError(t, vecto.Use(recordingInterceptor(&order, "tenant"), MiddlewareOptions{Name: "tenant", Before: "auth"}))
+	assert.NoError(t, vecto.Use(recordingInterceptor(&order, "sign"), MiddlewareOptions{Name: "sign", After: "auth"}))
+	assert.NoError(t, vecto.Use(recordingInterceptor(&order, "digest"), MiddlewareOptions{Name: "digest", After: "auth"}))
+	assert.NoError(t, vecto.Use(recordingInterceptor(&order, "low"), MiddlewareOptions{Name: "low", Priority: -1}))
+	assert.NoError(t, vecto.Use(recordingInterceptor(&order, "attempt"), MiddlewareOptions{Name: "attempt", Scope: MiddlewareScopeAttempt}))
+
+	_, err := vecto.Get(context.Background(), "/test/pets/1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tenant", "auth", "sign", "digest", "anonymous", "logging", "low", "attempt"}, order)
+
+	var names []string
+	for _, info := range vecto.ListMiddleware() {
+		names = append(names, info.Name)
+	}
+	assert.Equal(t, []string{"tenant", "auth", "sign", "digest", "", "logging", "low", "attempt"}, names)
+
+	list := vecto.ListMiddleware()
+	assert.Equal(t, MiddlewareInfo{Name: "sign", Kind: "interceptor", Scope: MiddlewareScopeCall, After: "auth"}, list[2])
+	assert.Equal(t, MiddlewareInfo{Name: "auth", Kind: "interceptor", Scope: MiddlewareScopeCall, Priority: 10}, list[1])
+	assert.Equal(t, MiddlewareScopeAttempt, list[7].Scope)
+}
+
+func TestRemoveMiddleware(t *testing.T) {
+	srv := newHTTPTestServer()
+	defer srv.Close()
+
+	vecto, _ := New(Config{BaseURL: srv.URL})
+
+	var order []string
+	assert.NoError(t, vecto.Use(recordingInterceptor(&order, "first"), MiddlewareOptions{Name: "first"}))
+	assert.NoError(t, vecto.Use(recordingInterceptor(&order, "auth"), MiddlewareOptions{Name: "auth", Priority: 5}))
+	assert.NoError(t, vecto.Use(recordingInterceptor(&order, "sign"), MiddlewareOptions{Name: "sign", After: "auth", Priority: -1}))
+
+	assert.True(t, vecto.RemoveMiddleware("auth"))
+	assert.False(t, vecto.RemoveMiddleware("auth"))
+	assert.False(t, vecto.RemoveMiddleware(""))
+
+	_, err := vecto.Get(context.Background(), "/test/pets/1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first", "sign"}, order, "sign falls back to its priority")
+
+	assert.NoError(t, vecto.Use(recordingInterceptor(&order, "auth"), MiddlewareOptions{Name: "auth", Before: "first"}))
+	order = nil
+	_, err = vecto.Get(context.Background(), "/test/pets/1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"auth", "sign", "first"}, order, "sign follows auth again")
+}
+
+func TestUseMiddlewareValidation(t *testing.T) {
+	vecto, _ := New(Config{})
+
+	noop := func(ctx context.Context, req *Request, next Next) (*Response, error) {
+		return next(ctx, req)
+	}
+
+	assert.NoError(t, vecto.Use(noop, MiddlewareOptions{Name: "auth"}))
+	assert.NoError(t, vecto.Use(noop, MiddlewareOptions{Name: "retry-log", Scope: MiddlewareScopeAttempt}))
+
+	tests := []struct {
+		name string
+		opts MiddlewareOptions
+	}{
+		{name: "duplicate name", opts: MiddlewareOptions{Name: "auth"}},
+		{name: "unknown anchor", opts: MiddlewareOptions{Before: "missing"}},
+		{name: "before and after", opts: MiddlewareOptions{Before: "auth", After: "auth"}},
+		{name: "other scope", opts: MiddlewareOptions{After: "retry-log"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, vecto.Use(noop, tt.opts))
+		})
+	}
+
+	assert.Error(t, vecto.Use(nil, MiddlewareOptions{}))
+	assert.Len(t, vecto.ListMiddleware(), 2)
+}
+
+func TestRequestOptionsMiddleware(t *testing.T) {
+	srv := newHTTPTestServer()
+	defer srv.Close()
+
+	vecto, _ := New(Config{BaseURL: srv.URL})
+
+	var order []string
+	vecto.UseRequest(func(ctx context.Context, req *Request) (*Request, error) {
+		order = append(order, "client request")
+		return req, nil
+	})
+	vecto.UseResponse(func(ctx context.Context, res *Response) (*Response, error) {
+		order = append(order, "client response")
+		return res, nil
+	})
+	assert.NoError(t, vecto.Use(recordingInterceptor(&order, "client"), MiddlewareOptions{Name: "client", Priority: -10}))
+
+	_, err := vecto.Get(context.Background(), "/test/pets/1", &RequestOptions{
+		Middleware: []Interceptor{
+			recordingInterceptor(&order, "per-request"),
+			ResponseInterceptor(func(ctx context.Context, res *Response) (*Response, error) {
+				order = append(order, "per-request response")
+				return res, nil
+			}),
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"client request", "client", "per-request", "per-request response", "client response"}, order)
+
+	order = nil
+	_, err = vecto.Get(context.Background(), "/test/pets/1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"client request", "client", "client response"}, order)
+	assert.Len(t, vecto.ListMiddleware(), 3)
+}
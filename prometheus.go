@@ -0,0 +1,362 @@
+package vecto
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	// DefaultDurationBuckets are the request duration histogram buckets, in seconds.
+	DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+	// DefaultSizeBuckets are the request and response size histogram buckets, in bytes.
+	DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+)
+
+// PrometheusConfig configures a PrometheusCollector.
+type PrometheusConfig struct {
+	// Namespace prefixes every metric name.
+	// Default: "vecto"
+	Namespace string
+
+	// DurationBuckets are the upper bounds of the duration histogram, in seconds.
+	// Default: DefaultDurationBuckets
+	DurationBuckets []float64
+
+	// SizeBuckets are the upper bounds of the size histograms, in bytes.
+	// Default: DefaultSizeBuckets
+	SizeBuckets []float64
+}
+
+// PrometheusCollector is a MetricsCollector that keeps request metrics in
+// memory and serves them in the Prometheus text exposition format.
+//
+// Example:
+//
+//	collector := vecto.NewPrometheusCollector(vecto.PrometheusConfig{})
+//	client, _ := vecto.New(vecto.Config{MetricsCollector: collector})
+//	http.Handle("/metrics", collector)
+type PrometheusCollector struct {
+	mu              sync.Mutex
+	namespace       string
+	durationBuckets []float64
+	sizeBuckets     []float64
+
+	requests      map[string]*promSeries
+	durations     map[string]*promHistogram
+	requestSizes  map[string]*promHistogram
+	responseSizes map[string]*promHistogram
+	inFlight      map[string]*promSeries
+	retries       map[string]*promSeries
+	fallbacks     map[string]*promSeries
+	breakerStates map[string]CircuitBreakerState
+}
+
+type promSeries struct {
+	labels []string
+	value  float64
+}
+
+type promHistogram struct {
+	labels []string
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewPrometheusCollector creates a PrometheusCollector.
+func NewPrometheusCollector(config PrometheusConfig) *PrometheusCollector {
+	namespace := config.Namespace
+	if namespace == "" {
+		namespace = "vecto"
+	}
+
+	durationBuckets := config.DurationBuckets
+	if len(durationBuckets) == 0 {
+		durationBuckets = DefaultDurationBuckets
+	}
+
+	sizeBuckets := config.SizeBuckets
+	if len(sizeBuckets) == 0 {
+		sizeBuckets = DefaultSizeBuckets
+	}
+
+	return &PrometheusCollector{
+		namespace:       namespace,
+		durationBuckets: normalizeBuckets(durationBuckets),
+		sizeBuckets:     normalizeBuckets(sizeBuckets),
+		requests:        make(map[string]*promSeries, 16),
+		durations:       make(map[string]*promHistogram, 16),
+		requestSizes:    make(map[string]*promHistogram, 16),
+		responseSizes:   make(map[string]*promHistogram, 16),
+		inFlight:        make(map[string]*promSeries, 16),
+		retries:         make(map[string]*promSeries, 16),
+		fallbacks:       make(map[string]*promSeries, 16),
+		breakerStates:   make(map[string]CircuitBreakerState, 4),
+	}
+}
+
+// normalizeBuckets sorts the buckets and drops duplicates and +Inf,
+// which is always added.
+func normalizeBuckets(buckets []float64) []float64 {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	result := sorted[:0]
+	for i, b := range sorted {
+		if math.IsInf(b, 1) || math.IsNaN(b) || (i > 0 && b == sorted[i-1]) {
+			continue
+		}
+		result = append(result, b)
+	}
+
+	return result
+}
+
+// statusClass returns "2xx", "4xx", etc., or "error" when no response was received.
+func statusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "error"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
+
+// RecordRequest implements MetricsCollector. Requests are labeled with their
+// route, or their normalized URL if they have no route. Requests served by a
+// fallback are counted with the status class of the fallback response.
+func (p *PrometheusCollector) RecordRequest(ctx context.Context, metrics RequestMetrics) {
+	class := statusClass(metrics.StatusCode)
+
+	route := metrics.Route
+	if route == "" {
+		route = metrics.URL
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.addCounter(p.requests, 1, metrics.Method, route, class)
+	p.observe(p.durations, p.durationBuckets, metrics.Duration.Seconds(), metrics.Method, route, class)
+	p.observe(p.requestSizes, p.sizeBuckets, float64(metrics.RequestSize), metrics.Method, route)
+	if metrics.StatusCode > 0 && !metrics.Fallback {
+		p.observe(p.responseSizes, p.sizeBuckets, float64(metrics.ResponseSize), metrics.Method, route, class)
+	}
+	if metrics.Fallback {
+		p.addCounter(p.fallbacks, 1, metrics.Method, route)
+	}
+}
+
+// RequestStarted implements InFlightRecorder.
+func (p *PrometheusCollector) RequestStarted(ctx context.Context, method, route string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addCounter(p.inFlight, 1, method, route)
+}
+
+// RequestFinished implements InFlightRecorder.
+func (p *PrometheusCollector) RequestFinished(ctx context.Context, method, route string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addCounter(p.inFlight, -1, method, route)
+}
+
+// RecordRetry implements RetryRecorder.
+func (p *PrometheusCollector) RecordRetry(ctx context.Context, method, route string, attempt int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addCounter(p.retries, 1, method, route)
+}
+
+// RecordCircuitBreakerState implements CircuitBreakerStateRecorder.
+func (p *PrometheusCollector) RecordCircuitBreakerState(key string, state CircuitBreakerState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.breakerStates[key] = state
+}
+
+func seriesKey(labels []string) string {
+	return strings.Join(labels, "\xff")
+}
+
+func (p *PrometheusCollector) addCounter(series map[string]*promSeries, delta float64, labels ...string) {
+	key := seriesKey(labels)
+	s, ok := series[key]
+	if !ok {
+		s = &promSeries{labels: labels}
+		series[key] = s
+	}
+	s.value += delta
+}
+
+func (p *PrometheusCollector) observe(series map[string]*promHistogram, buckets []float64, value float64, labels ...string) {
+	key := seriesKey(labels)
+	h, ok := series[key]
+	if !ok {
+		h = &promHistogram{labels: labels, counts: make([]uint64, len(buckets))}
+		series[key] = h
+	}
+
+	for i, upper := range buckets {
+		if value <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (p *PrometheusCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	p.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (p *PrometheusCollector) WriteTo(w io.Writer) (int64, error) {
+	// Render into memory first so a slow reader never holds up the
+	// collector's lock, which every outgoing request also takes.
+	var buf bytes.Buffer
+
+	p.mu.Lock()
+	p.writeCounters(&buf, "http_client_requests_total", "counter", "Total number of HTTP requests.",
+		p.requests, "method", "route", "status_class")
+	p.writeHistograms(&buf, "http_client_request_duration_seconds", "Duration of HTTP requests in seconds.",
+		p.durations, p.durationBuckets, "method", "route", "status_class")
+	p.writeHistograms(&buf, "http_client_request_size_bytes", "Size of HTTP request bodies in bytes.",
+		p.requestSizes, p.sizeBuckets, "method", "route")
+	p.writeHistograms(&buf, "http_client_response_size_bytes", "Size of HTTP response bodies in bytes.",
+		p.responseSizes, p.sizeBuckets, "method", "route", "status_class")
+	p.writeCounters(&buf, "http_client_requests_in_flight", "gauge", "Number of HTTP requests in progress.",
+		p.inFlight, "method", "route")
+	p.writeCounters(&buf, "http_client_retries_total", "counter", "Total number of HTTP request retries.",
+		p.retries, "method", "route")
+	p.writeCounters(&buf, "http_client_fallbacks_total", "counter", "Total number of fallback responses served.",
+		p.fallbacks, "method", "route")
+	p.writeBreakerStates(&buf)
+	p.mu.Unlock()
+
+	return buf.WriteTo(w)
+}
+
+func (p *PrometheusCollector) writeHeader(w io.Writer, name, kind, help string) string {
+	fullName := p.namespace + "_" + name
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", fullName, help, fullName, kind)
+	return fullName
+}
+
+func (p *PrometheusCollector) writeCounters(w io.Writer, name, kind, help string, series map[string]*promSeries, labelNames ...string) {
+	if len(series) == 0 {
+		return
+	}
+
+	fullName := p.writeHeader(w, name, kind, help)
+	for _, key := range sortedKeys(series) {
+		s := series[key]
+		fmt.Fprintf(w, "%s%s %s\n", fullName, formatLabels(labelNames, s.labels), formatFloat(s.value))
+	}
+}
+
+func (p *PrometheusCollector) writeHistograms(w io.Writer, name, help string, series map[string]*promHistogram, buckets []float64, labelNames ...string) {
+	if len(series) == 0 {
+		return
+	}
+
+	fullName := p.writeHeader(w, name, "histogram", help)
+	bucketLabels := append(append([]string(nil), labelNames...), "le")
+
+	for _, key := range sortedKeys(series) {
+		h := series[key]
+		for i, upper := range buckets {
+			labels := append(append([]string(nil), h.labels...), formatFloat(upper))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", fullName, formatLabels(bucketLabels, labels), h.counts[i])
+		}
+		labels := append(append([]string(nil), h.labels...), "+Inf")
+		fmt.Fprintf(w, "%s_bucket%s %d\n", fullName, formatLabels(bucketLabels, labels), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", fullName, formatLabels(labelNames, h.labels), formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", fullName, formatLabels(labelNames, h.labels), h.count)
+	}
+}
+
+// writeBreakerStates writes one series per breaker and state, set to 1 for
+// the current state and 0 for the others.
+func (p *PrometheusCollector) writeBreakerStates(w io.Writer) {
+	if len(p.breakerStates) == 0 {
+		return
+	}
+
+	fullName := p.writeHeader(w, "circuit_breaker_state", "gauge", "Current circuit breaker state (1 for the active state).")
+
+	keys := make([]string, 0, len(p.breakerStates))
+	for key := range p.breakerStates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		current := p.breakerStates[key]
+		for _, state := range []CircuitBreakerState{StateClosed, StateOpen, StateHalfOpen} {
+			value := 0
+			if state == current {
+				value = 1
+			}
+			fmt.Fprintf(w, "%s%s %d\n", fullName, formatLabels([]string{"key", "state"}, []string{key, state.String()}), value)
+		}
+	}
+}
+
+func sortedKeys[T any](series map[string]T) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,202 @@
+package vecto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, collector *PrometheusCollector) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	collector.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, prometheusContentType, rec.Header().Get("Content-Type"))
+
+	return rec.Body.String()
+}
+
+func TestPrometheusCollector_Exposition(t *testing.T) {
+	collector := NewPrometheusCollector(PrometheusConfig{
+		Namespace:       "api",
+		DurationBuckets: []float64{1, 0.1, 0.1},
+		SizeBuckets:     []float64{100},
+	})
+
+	collector.RecordRequest(context.Background(), RequestMetrics{
+		Method:       http.MethodGet,
+		URL:          "http://example.com/pets",
+		StatusCode:   http.StatusOK,
+		Duration:     50 * time.Millisecond,
+		ResponseSize: 20,
+	})
+	collector.RecordRequest(context.Background(), RequestMetrics{
+		Method:       http.MethodGet,
+		URL:          "http://example.com/pets",
+		StatusCode:   http.StatusOK,
+		Duration:     500 * time.Millisecond,
+		ResponseSize: 200,
+	})
+	collector.RecordRequest(context.Background(), RequestMetrics{
+		Method: http.MethodPost,
+		URL:    `http://example.com/"quoted"`,
+		Error:  errors.New("connection refused"),
+	})
+	collector.RecordRequest(context.Background(), RequestMetrics{
+		Method:     http.MethodGet,
+		URL:        "http://example.com/cached",
+		StatusCode: http.StatusOK,
+		Error:      errors.New("circuit breaker is open"),
+		Fallback:   true,
+	})
+
+	output := scrape(t, collector)
+
+	expected := []string{
+		"# HELP api_http_client_requests_total Total number of HTTP requests.",
+		"# TYPE api_http_client_requests_total counter",
+		`api_http_client_requests_total{method="GET",route="http://example.com/pets",status_class="2xx"} 2`,
+		`api_http_client_requests_total{method="POST",route="http://example.com/\"quoted\"",status_class="error"} 1`,
+		"# TYPE api_http_client_request_duration_seconds histogram",
+		`api_http_client_request_duration_seconds_bucket{method="GET",route="http://example.com/pets",status_class="2xx",le="0.1"} 1`,
+		`api_http_client_request_duration_seconds_bucket{method="GET",route="http://example.com/pets",status_class="2xx",le="1"} 2`,
+		`api_http_client_request_duration_seconds_bucket{method="GET",route="http://example.com/pets",status_class="2xx",le="+Inf"} 2`,
+		`api_http_client_request_duration_seconds_sum{method="GET",route="http://example.com/pets",status_class="2xx"} 0.55`,
+		`api_http_client_request_duration_seconds_count{method="GET",route="http://example.com/pets",status_class="2xx"} 2`,
+		`api_http_client_response_size_bytes_bucket{method="GET",route="http://example.com/pets",status_class="2xx",le="100"} 1`,
+		`api_http_client_response_size_bytes_sum{method="GET",route="http://example.com/pets",status_class="2xx"} 220`,
+		`api_http_client_requests_total{method="GET",route="http://example.com/cached",status_class="2xx"} 1`,
+		`api_http_client_fallbacks_total{method="GET",route="http://example.com/cached"} 1`,
+	}
+	for _, line := range expected {
+		assert.Contains(t, output, line+"\n")
+	}
+
+	assert.NotContains(t, output, `api_http_client_response_size_bytes_count{method="POST"`)
+	assert.NotContains(t, output, "circuit_breaker_state")
+}
+
+func TestPrometheusCollector_Client(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			started <- struct{}{}
+			<-release
+		case "/flaky":
+			if atomic.AddInt32(&calls, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	collector := NewPrometheusCollector(PrometheusConfig{})
+	cbConfig := DefaultCircuitBreakerConfig()
+	cbConfig.FailureThreshold = 1
+
+	v, err := New(Config{
+		BaseURL:          srv.URL,
+		MetricsCollector: collector,
+		Retry:            &RetryConfig{MaxAttempts: 2, WaitTime: time.Millisecond},
+		CircuitBreaker:   &cbConfig,
+	})
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = v.Get(context.Background(), "/slow", nil)
+	}()
+	<-started
+
+	slowURL := srv.URL + "/slow"
+	output := scrape(t, collector)
+	assert.Contains(t, output, `vecto_http_client_requests_in_flight{method="GET",route="`+slowURL+`"} 1`)
+	assert.Contains(t, output, `vecto_circuit_breaker_state{key="`+srv.URL+`",state="closed"} 1`, "new breakers are reported as closed")
+	close(release)
+	<-done
+	assert.Contains(t, scrape(t, collector), `vecto_http_client_requests_in_flight{method="GET",route="`+slowURL+`"} 0`)
+
+	_, err = v.Get(context.Background(), "/flaky", nil)
+	require.NoError(t, err)
+
+	_, _ = v.Get(context.Background(), "/broken", nil)
+
+	output = scrape(t, collector)
+	assert.Contains(t, output, `vecto_http_client_retries_total{method="GET",route="`+srv.URL+`/flaky"} 1`)
+	assert.Contains(t, output, `vecto_http_client_requests_total{method="GET",route="`+srv.URL+`/flaky",status_class="2xx"} 1`)
+	assert.Contains(t, output, `vecto_circuit_breaker_state{key="`+srv.URL+`",state="open"} 1`)
+	assert.Contains(t, output, `vecto_circuit_breaker_state{key="`+srv.URL+`",state="closed"} 0`)
+
+	var b strings.Builder
+	n, err := collector.WriteTo(&b)
+	require.NoError(t, err)
+	assert.Equal(t, int64(b.Len()), n)
+	assert.Equal(t, output, b.String())
+}
+
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	close(w.started)
+	<-w.release
+	return len(p), nil
+}
+
+func TestPrometheusCollector_SlowScrapeDoesNotBlockRecording(t *testing.T) {
+	collector := NewPrometheusCollector(PrometheusConfig{})
+	// Enough series to overflow any small write buffer.
+	for i := 0; i < 100; i++ {
+		collector.RecordRequest(context.Background(), RequestMetrics{
+			Method:     http.MethodGet,
+			URL:        fmt.Sprintf("http://example.com/pets/%d", i),
+			StatusCode: http.StatusOK,
+		})
+	}
+
+	w := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	scraped := make(chan struct{})
+	go func() {
+		defer close(scraped)
+		_, _ = collector.WriteTo(w)
+	}()
+	<-w.started
+	defer func() {
+		close(w.release)
+		<-scraped
+	}()
+
+	recorded := make(chan struct{})
+	go func() {
+		defer close(recorded)
+		collector.RecordRequest(context.Background(), RequestMetrics{
+			Method:     http.MethodGet,
+			URL:        "http://example.com/pets",
+			StatusCode: http.StatusOK,
+		})
+	}()
+
+	select {
+	case <-recorded:
+	case <-time.After(time.Second):
+		t.Fatal("RecordRequest blocked while a scrape was writing")
+	}
+}
@@ -0,0 +1,539 @@
+package vecto
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProxyConfig configures the proxy used for outgoing requests.
+// HTTP and HTTPS proxies tunnel TLS traffic with CONNECT; SOCKS5 proxies
+// (socks5:// or socks5h://) are supported as well.
+type ProxyConfig struct {
+	// URL is the proxy address, e.g. "http://proxy:3128" or "socks5://proxy:1080".
+	// Credentials may be given in the URL or with Username and Password.
+	URL string
+
+	// Username and Password authenticate with the proxy. They take precedence
+	// over credentials in URL.
+	Username string
+	Password string
+
+	// FromEnvironment reads the proxy from HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// (or their lowercase forms) when the client is created. URL, if set, takes
+	// precedence over the environment proxies. Invalid NO_PROXY entries are
+	// logged and skipped.
+	FromEnvironment bool
+
+	// NoProxy lists hosts that are reached directly. Entries may be:
+	//   - "*" to bypass the proxy for every host
+	//   - a host name, matching the host and its subdomains ("example.com")
+	//   - a wildcard domain, matching subdomains only ("*.example.com" or ".example.com")
+	//   - an IP address or CIDR block ("10.1.2.3", "10.0.0.0/8")
+	// Host names and IP addresses may carry a port ("example.com:8443").
+	NoProxy []string
+}
+
+func validateProxyConfig(config *ProxyConfig) error {
+	if config == nil {
+		return nil
+	}
+
+	if config.URL == "" && !config.FromEnvironment {
+		return fmt.Errorf("proxy URL is required unless FromEnvironment is set")
+	}
+
+	if config.URL != "" {
+		if _, err := parseProxyURL(config.URL); err != nil {
+			return err
+		}
+	}
+
+	if config.Password != "" && config.Username == "" {
+		return fmt.Errorf("proxy password requires a username")
+	}
+
+	for i, entry := range config.NoProxy {
+		if _, err := parseBypassRule(entry); err != nil {
+			return fmt.Errorf("no proxy entry %d (%q): %w", i, entry, err)
+		}
+	}
+
+	return nil
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy URL scheme must be http, https, socks5 or socks5h, got: %q", proxyURL.Scheme)
+	}
+
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy URL must include a host")
+	}
+
+	return proxyURL, nil
+}
+
+// bypassRule is a parsed NoProxy entry.
+type bypassRule struct {
+	matchAll   bool
+	network    *net.IPNet
+	ip         net.IP
+	domain     string
+	subdomains bool
+	port       string
+}
+
+func parseBypassRule(entry string) (bypassRule, error) {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if entry == "" {
+		return bypassRule{}, fmt.Errorf("entry cannot be empty")
+	}
+
+	if entry == "*" {
+		return bypassRule{matchAll: true}, nil
+	}
+
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return bypassRule{}, fmt.Errorf("invalid CIDR: %w", err)
+		}
+		return bypassRule{network: network}, nil
+	}
+
+	host, port := entry, ""
+	if h, p, err := net.SplitHostPort(entry); err == nil {
+		host, port = h, p
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return bypassRule{ip: ip, port: port}, nil
+	}
+
+	rule := bypassRule{port: port}
+	switch {
+	case strings.HasPrefix(host, "*."):
+		rule.domain = host[2:]
+		rule.subdomains = true
+	case strings.HasPrefix(host, "."):
+		rule.domain = host[1:]
+		rule.subdomains = true
+	default:
+		rule.domain = host
+	}
+
+	if rule.domain == "" || strings.Contains(rule.domain, "*") {
+		return bypassRule{}, fmt.Errorf("invalid host pattern")
+	}
+
+	return rule, nil
+}
+
+func (r bypassRule) matches(host, port string) bool {
+	if r.matchAll {
+		return true
+	}
+
+	if r.port != "" && r.port != port {
+		return false
+	}
+
+	if r.network != nil || r.ip != nil {
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return false
+		}
+		if r.network != nil {
+			return r.network.Contains(ip)
+		}
+		return r.ip.Equal(ip)
+	}
+
+	if strings.HasSuffix(host, "."+r.domain) {
+		return true
+	}
+
+	return !r.subdomains && host == r.domain
+}
+
+// proxySelector decides which proxy, if any, is used for a request.
+type proxySelector struct {
+	httpProxy  *url.URL
+	httpsProxy *url.URL
+	bypass     []bypassRule
+}
+
+// newProxySelector builds the selector for config. Invalid NoProxy entries are
+// an error, while invalid NO_PROXY entries from the environment are logged and
+// skipped, as net/http does.
+func newProxySelector(config *ProxyConfig, logger Logger) (*proxySelector, error) {
+	if logger == nil {
+		logger = newNoopLogger()
+	}
+
+	selector := &proxySelector{}
+
+	if config.FromEnvironment {
+		var err error
+		if selector.httpProxy, err = proxyFromEnv("HTTP_PROXY"); err != nil {
+			return nil, err
+		}
+		if selector.httpsProxy, err = proxyFromEnv("HTTPS_PROXY"); err != nil {
+			return nil, err
+		}
+		for _, entry := range strings.Split(getEnvAny("NO_PROXY", "no_proxy"), ",") {
+			if strings.TrimSpace(entry) == "" {
+				continue
+			}
+			rule, err := parseBypassRule(entry)
+			if err != nil {
+				if !logger.IsNoop() {
+					logger.Warn(context.Background(), "ignoring invalid NO_PROXY entry", map[string]interface{}{
+						"entry": entry,
+						"error": err.Error(),
+					})
+				}
+				continue
+			}
+			selector.bypass = append(selector.bypass, rule)
+		}
+	}
+
+	if config.URL != "" {
+		proxyURL, err := parseProxyURL(config.URL)
+		if err != nil {
+			return nil, err
+		}
+		selector.httpProxy = proxyURL
+		selector.httpsProxy = proxyURL
+	}
+
+	if config.Username != "" {
+		user := url.UserPassword(config.Username, config.Password)
+		for _, proxyURL := range []*url.URL{selector.httpProxy, selector.httpsProxy} {
+			if proxyURL != nil {
+				proxyURL.User = user
+			}
+		}
+	}
+
+	for _, entry := range config.NoProxy {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		rule, err := parseBypassRule(entry)
+		if err != nil {
+			return nil, fmt.Errorf("no proxy entry %q: %w", entry, err)
+		}
+		selector.bypass = append(selector.bypass, rule)
+	}
+
+	return selector, nil
+}
+
+func proxyFromEnv(name string) (*url.URL, error) {
+	value := getEnvAny(name, strings.ToLower(name))
+	if value == "" {
+		return nil, nil
+	}
+
+	if !strings.Contains(value, "://") {
+		value = "http://" + value
+	}
+
+	proxyURL, err := parseProxyURL(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	return proxyURL, nil
+}
+
+func getEnvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// proxyFunc is used as http.Transport.Proxy.
+func (s *proxySelector) proxyFunc(req *http.Request) (*url.URL, error) {
+	proxyURL := s.httpProxy
+	if req.URL.Scheme == "https" {
+		proxyURL = s.httpsProxy
+	}
+
+	if proxyURL == nil {
+		return nil, nil
+	}
+
+	if s.shouldBypass(req.URL) {
+		return nil, nil
+	}
+
+	return proxyURL, nil
+}
+
+func (s *proxySelector) shouldBypass(target *url.URL) bool {
+	host := strings.ToLower(target.Hostname())
+	port := target.Port()
+	if port == "" {
+		switch target.Scheme {
+		case "https":
+			port = "443"
+		case "http":
+			port = "80"
+		}
+	}
+
+	for _, rule := range s.bypass {
+		if rule.matches(host, port) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SOCKS5 protocol values (RFC 1928 and RFC 1929).
+const (
+	socks5Version      = 5
+	socks5NoAuth       = 0
+	socks5UserPassAuth = 2
+	socks5CmdConnect   = 1
+	socks5IPv4         = 1
+	socks5Domain       = 3
+	socks5IPv6         = 4
+)
+
+// dialProxy connects to addr through proxyURL, using CONNECT for HTTP and
+// HTTPS proxies and the SOCKS5 protocol for socks5 and socks5h ones. It backs
+// the TLS dialer installed for host-aware verification, which net/http would
+// otherwise bypass for proxied requests. The handshake with the proxy is
+// abandoned when ctx is done.
+func dialProxy(ctx context.Context, dial DialContextFunc, transport *http.Transport, proxyURL *url.URL, addr string) (net.Conn, error) {
+	conn, err := dial(ctx, "tcp", proxyAddr(proxyURL))
+	if err != nil {
+		return nil, err
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		err = socks5Connect(conn, proxyURL.User, addr)
+	case "https":
+		tlsConfig := &tls.Config{ServerName: proxyURL.Hostname()}
+		if transport.TLSClientConfig != nil {
+			tlsConfig = transport.TLSClientConfig.Clone()
+			tlsConfig.ServerName = proxyURL.Hostname()
+			tlsConfig.NextProtos = nil
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err = tlsConn.HandshakeContext(ctx); err == nil {
+			conn = tlsConn
+			err = httpConnect(ctx, conn, transport, proxyURL, addr)
+		}
+	default:
+		err = httpConnect(ctx, conn, transport, proxyURL, addr)
+	}
+
+	if !stop() && err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %w", proxyURL.Redacted(), err)
+	}
+
+	return conn, nil
+}
+
+// proxyAddr returns the host and port of proxyURL, with the default port of
+// its scheme when none is given.
+func proxyAddr(proxyURL *url.URL) string {
+	if port := proxyURL.Port(); port != "" {
+		return net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	port := "80"
+	switch proxyURL.Scheme {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+// httpConnect opens a tunnel to addr with a CONNECT request.
+func httpConnect(ctx context.Context, conn net.Conn, transport *http.Transport, proxyURL *url.URL, addr string) error {
+	// As in net/http, GetProxyConnectHeader replaces ProxyConnectHeader.
+	header := transport.ProxyConnectHeader
+	if transport.GetProxyConnectHeader != nil {
+		var err error
+		if header, err = transport.GetProxyConnectHeader(ctx, proxyURL, addr); err != nil {
+			return err
+		}
+	}
+	header = header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	if user := proxyURL.User; user != nil && header.Get("Proxy-Authorization") == "" {
+		password, _ := user.Password()
+		header.Set("Proxy-Authorization", encodeBasicAuth(user.Username(), password))
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: header,
+	}
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+
+	// The target only speaks after the client hello, so nothing past the
+	// response is buffered. The body is left unread: a successful CONNECT
+	// response has none and is followed by the tunnel.
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("CONNECT to %s failed: %s", addr, res.Status)
+	}
+
+	return nil
+}
+
+// socks5Connect opens a connection to addr with the SOCKS5 protocol
+// (RFC 1928), authenticating with username and password (RFC 1929) when
+// user is set. Host names are resolved by the proxy.
+func socks5Connect(conn net.Conn, user *url.Userinfo, addr string) error {
+	host, portString, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portString)
+	}
+
+	methods := []byte{socks5NoAuth}
+	if user != nil {
+		methods = append(methods, socks5UserPassAuth)
+	}
+	if _, err := conn.Write(append([]byte{socks5Version, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("unexpected SOCKS version %d", reply[0])
+	}
+
+	switch reply[1] {
+	case socks5NoAuth:
+	case socks5UserPassAuth:
+		if user == nil {
+			return fmt.Errorf("SOCKS5 proxy requires authentication")
+		}
+		username := user.Username()
+		password, _ := user.Password()
+		if len(username) > 255 || len(password) > 255 {
+			return fmt.Errorf("SOCKS5 username and password are limited to 255 bytes")
+		}
+
+		auth := []byte{1, byte(len(username))}
+		auth = append(auth, username...)
+		auth = append(auth, byte(len(password)))
+		auth = append(auth, password...)
+		if _, err := conn.Write(auth); err != nil {
+			return err
+		}
+
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0 {
+			return fmt.Errorf("SOCKS5 authentication failed")
+		}
+	default:
+		return fmt.Errorf("no acceptable SOCKS5 authentication method")
+	}
+
+	request := []byte{socks5Version, socks5CmdConnect, 0}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			request = append(append(request, socks5IPv4), ip4...)
+		} else {
+			request = append(append(request, socks5IPv6), ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("host name %q is too long for SOCKS5", host)
+		}
+		request = append(append(request, socks5Domain, byte(len(host))), host...)
+	}
+	request = binary.BigEndian.AppendUint16(request, uint16(port))
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0 {
+		return fmt.Errorf("SOCKS5 connect to %s failed with code %d", addr, header[1])
+	}
+
+	var boundLen int
+	switch header[3] {
+	case socks5IPv4:
+		boundLen = net.IPv4len
+	case socks5IPv6:
+		boundLen = net.IPv6len
+	case socks5Domain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return err
+		}
+		boundLen = int(length[0])
+	default:
+		return fmt.Errorf("unexpected SOCKS5 address type %d", header[3])
+	}
+
+	_, err = io.ReadFull(conn, make([]byte, boundLen+2))
+	return err
+}
@@ -0,0 +1,266 @@
+package vecto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultRedactionReplacement replaces redacted values.
+const DefaultRedactionReplacement = "[REDACTED]"
+
+// DefaultSensitiveHeaders are the headers always redacted unless redaction is disabled.
+var DefaultSensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+}
+
+// RedactionConfig configures the redaction of secrets in debug output,
+// cURL commands, HAR files, log fields and the URL of tracing spans.
+// Redaction is on by default.
+type RedactionConfig struct {
+	// Disabled turns redaction off, e.g. for local debugging.
+	// Default: false
+	Disabled bool
+
+	// Headers are header names redacted in addition to DefaultSensitiveHeaders.
+	// Names are case-insensitive.
+	// Default: nil
+	Headers []string
+
+	// QueryParams are the query parameters whose values are redacted.
+	// Names are case-insensitive.
+	// Default: nil
+	QueryParams []string
+
+	// BodyFields are the JSON body fields whose values are redacted, as
+	// dot-separated paths from the root object such as "password" or
+	// "credentials.token". A "*" segment matches any key, and arrays are
+	// traversed, so "users.password" covers {"users": [{"password": ...}]}.
+	// Default: nil
+	BodyFields []string
+
+	// Replacement replaces redacted values.
+	// Default: DefaultRedactionReplacement
+	Replacement string
+}
+
+func validateRedactionConfig(config *RedactionConfig) error {
+	if config == nil {
+		return nil
+	}
+
+	for _, path := range config.BodyFields {
+		for _, segment := range strings.Split(path, ".") {
+			if segment == "" {
+				return fmt.Errorf("invalid body field path %q", path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// redactor applies a RedactionConfig. A nil *redactor redacts nothing.
+type redactor struct {
+	disabled    bool
+	headers     map[string]bool
+	queryParams map[string]bool
+	bodyFields  [][]string
+	replacement string
+}
+
+// defaultRedactor redacts the requests that were not created by a client.
+var defaultRedactor = newRedactor(nil)
+
+func newRedactor(config *RedactionConfig) *redactor {
+	if config == nil {
+		config = &RedactionConfig{}
+	}
+
+	if config.Disabled {
+		return &redactor{disabled: true}
+	}
+
+	r := &redactor{
+		headers:     make(map[string]bool, len(DefaultSensitiveHeaders)+len(config.Headers)),
+		queryParams: make(map[string]bool, len(config.QueryParams)),
+		replacement: config.Replacement,
+	}
+
+	if r.replacement == "" {
+		r.replacement = DefaultRedactionReplacement
+	}
+
+	for _, name := range DefaultSensitiveHeaders {
+		r.headers[strings.ToLower(name)] = true
+	}
+	for _, name := range config.Headers {
+		r.headers[strings.ToLower(name)] = true
+	}
+	for _, name := range config.QueryParams {
+		r.queryParams[strings.ToLower(name)] = true
+	}
+	for _, path := range config.BodyFields {
+		r.bodyFields = append(r.bodyFields, strings.Split(path, "."))
+	}
+
+	return r
+}
+
+func (r *redactor) off() bool {
+	return r == nil || r.disabled
+}
+
+// header returns the value of the header name, redacted if it is sensitive.
+func (r *redactor) header(name, value string) string {
+	if r.off() || !r.headers[strings.ToLower(name)] {
+		return value
+	}
+	return r.replacement
+}
+
+// headerValues returns a copy of headers with the sensitive values redacted.
+func (r *redactor) headerValues(headers map[string][]string) map[string][]string {
+	if headers == nil {
+		return nil
+	}
+
+	result := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		redacted := make([]string, len(values))
+		for i, value := range values {
+			redacted[i] = r.header(name, value)
+		}
+		result[name] = redacted
+	}
+
+	return result
+}
+
+// url returns rawURL with its password and sensitive query parameters redacted.
+func (r *redactor) url(rawURL string) string {
+	if r.off() {
+		return rawURL
+	}
+
+	if u, err := url.Parse(rawURL); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			rawURL = u.Redacted()
+		}
+	}
+
+	if len(r.queryParams) == 0 {
+		return rawURL
+	}
+
+	start := strings.IndexByte(rawURL, '?')
+	if start < 0 {
+		return rawURL
+	}
+
+	end := len(rawURL)
+	if fragment := strings.IndexByte(rawURL[start:], '#'); fragment >= 0 {
+		end = start + fragment
+	}
+
+	pairs := strings.Split(rawURL[start+1:end], "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && r.queryParams[strings.ToLower(name)] {
+			pairs[i] = key + "=" + r.replacement
+		}
+	}
+
+	return rawURL[:start+1] + strings.Join(pairs, "&") + rawURL[end:]
+}
+
+// body returns a JSON body with the configured fields redacted. Bodies that
+// are not JSON, or have none of the fields, are returned unchanged.
+func (r *redactor) body(body []byte) []byte {
+	if r.off() || len(r.bodyFields) == 0 || len(body) == 0 {
+		return body
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil || decoder.More() {
+		return body
+	}
+
+	changed := false
+	for _, path := range r.bodyFields {
+		if redactJSONPath(doc, path, r.replacement) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return body
+	}
+
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return body
+	}
+
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
+
+// logFields redacts rawURL wherever it appears in the string fields, as
+// url.Error messages include the request URL verbatim.
+func (r *redactor) logFields(fields map[string]interface{}, rawURL string) {
+	if r.off() || rawURL == "" {
+		return
+	}
+
+	redacted := r.url(rawURL)
+	if redacted == rawURL {
+		return
+	}
+
+	for key, value := range fields {
+		if s, ok := value.(string); ok {
+			fields[key] = strings.ReplaceAll(s, rawURL, redacted)
+		}
+	}
+}
+
+func redactJSONPath(node interface{}, path []string, replacement string) bool {
+	switch n := node.(type) {
+	case []interface{}:
+		changed := false
+		for _, elem := range n {
+			if redactJSONPath(elem, path, replacement) {
+				changed = true
+			}
+		}
+		return changed
+	case map[string]interface{}:
+		changed := false
+		for key, value := range n {
+			if path[0] != "*" && path[0] != key {
+				continue
+			}
+			if len(path) == 1 {
+				n[key] = replacement
+				changed = true
+			} else if redactJSONPath(value, path[1:], replacement) {
+				changed = true
+			}
+		}
+		return changed
+	}
+
+	return false
+}
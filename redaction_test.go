@@ -0,0 +1,130 @@
+package vecto
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor(t *testing.T) {
+	rd := newRedactor(&RedactionConfig{
+		Headers:     []string{"X-Session"},
+		QueryParams: []string{"api_key"},
+		BodyFields:  []string{"password", "users.token", "*.secret"},
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		assert.Equal(t, "[REDACTED]", rd.header("authorization", "Bearer abc"))
+		assert.Equal(t, "[REDACTED]", rd.header("X-SESSION", "abc"))
+		assert.Equal(t, "application/json", rd.header("Content-Type", "application/json"))
+
+		headers := rd.headerValues(map[string][]string{"Set-Cookie": {"a=1", "b=2"}})
+		assert.Equal(t, []string{"[REDACTED]", "[REDACTED]"}, headers["Set-Cookie"])
+	})
+
+	t.Run("urls", func(t *testing.T) {
+		assert.Equal(t, "https://h/p?page=2&api_key=[REDACTED]#top", rd.url("https://h/p?page=2&api_key=s3cr3t#top"))
+		assert.Equal(t, "https://user:xxxxx@h/p", rd.url("https://user:pass@h/p"))
+		assert.Equal(t, "https://h/p", rd.url("https://h/p"))
+	})
+
+	t.Run("bodies", func(t *testing.T) {
+		body := `{"password":"p","name":"<n>","users":[{"token":"t","id":1}],"db":{"secret":"s"}}`
+		assert.JSONEq(t,
+			`{"password":"[REDACTED]","name":"<n>","users":[{"token":"[REDACTED]","id":1}],"db":{"secret":"[REDACTED]"}}`,
+			string(rd.body([]byte(body))))
+
+		assert.Equal(t, `{"name":"n"}`, string(rd.body([]byte(`{"name":"n"}`))))
+		assert.Equal(t, "password=p", string(rd.body([]byte("password=p"))))
+		assert.Equal(t, `{"password":"[REDACTED]"}`, string(rd.body([]byte(`{"password":"p"}`))))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		off := newRedactor(&RedactionConfig{Disabled: true, QueryParams: []string{"api_key"}})
+		assert.Equal(t, "Bearer abc", off.header("Authorization", "Bearer abc"))
+		assert.Equal(t, "https://h/p?api_key=k", off.url("https://h/p?api_key=k"))
+	})
+
+	_, err := New(Config{Redaction: &RedactionConfig{BodyFields: []string{"a..b"}}})
+	assert.Error(t, err)
+}
+
+func TestRedaction_Client(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "cookie-secret"})
+		w.Write([]byte(`{"access_token":"token-secret"}`))
+	}))
+	defer srv.Close()
+
+	newClient := func(redaction *RedactionConfig) (*Vecto, *mockLogger) {
+		logger := &mockLogger{}
+		v, err := New(Config{
+			BaseURL:   srv.URL,
+			Logger:    logger,
+			DebugMode: true,
+			Redaction: redaction,
+			Headers:   map[string]string{"Authorization": "Bearer header-secret"},
+		})
+		require.NoError(t, err)
+		return v, logger
+	}
+
+	redaction := &RedactionConfig{
+		QueryParams: []string{"api_key"},
+		BodyFields:  []string{"password", "access_token"},
+	}
+	options := &RequestOptions{
+		Params: map[string]any{"api_key": "query-secret"},
+		Data:   map[string]string{"user": "bob", "password": "body-secret"},
+	}
+
+	v, logger := newClient(redaction)
+	res, err := v.Post(context.Background(), "/login", options)
+	require.NoError(t, err)
+
+	var output strings.Builder
+	for _, call := range logger.debugCalls {
+		output.WriteString(call.msg)
+	}
+	for _, call := range append(logger.infoCalls, logger.debugCalls...) {
+		for _, value := range call.fields {
+			output.WriteString(" ")
+			output.WriteString(toString(value))
+		}
+	}
+	output.WriteString(res.request.ToCurl())
+
+	for _, secret := range []string{"header-secret", "query-secret", "body-secret", "cookie-secret", "token-secret"} {
+		assert.NotContains(t, output.String(), secret)
+	}
+	assert.Contains(t, output.String(), "api_key=[REDACTED]")
+	assert.Contains(t, output.String(), `"user":"bob"`)
+
+	t.Run("redacts the URL in error messages", func(t *testing.T) {
+		fields := v.requestLogFields(context.Background(), res.request, map[string]interface{}{
+			"error": errors.New(`Post "` + res.request.FullUrl() + `": EOF`).Error(),
+		})
+		assert.NotContains(t, fields["error"], "query-secret")
+		assert.NotContains(t, fields["url"], "query-secret")
+	})
+
+	t.Run("can be disabled", func(t *testing.T) {
+		v, _ := newClient(&RedactionConfig{Disabled: true})
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Contains(t, res.request.ToCurl(), "Authorization: Bearer header-secret")
+	})
+}
+
+func toString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return ""
+}
@@ -20,15 +20,18 @@ type Request struct {
 	host        string
 	scheme      string
 	path        string
+	route       string
 	method      string
 	params      map[string]any
 	headers     map[string]string
 	data        interface{}
+	body        []byte
 	transform   RequestTransformFunc
 	rawReq      *http.Request
 	events      requestEvents
 	cbKey       string
 	cbKeyCached bool
+	redactor    *redactor
 }
 
 // OnCompleted registers a channel that will receive a RequestCompletedEvent when the request completes.
@@ -172,6 +175,44 @@ func (r *Request) Path() string {
 	return r.path
 }
 
+// encodedBody returns the body sent for the request, encoding its data with
+// the request transform if it was not sent yet.
+func (r *Request) encodedBody() []byte {
+	r.mu.RLock()
+	body, data, transform := r.body, r.data, r.transform
+	r.mu.RUnlock()
+
+	if body != nil || data == nil {
+		return body
+	}
+
+	if transform != nil {
+		if encoded, err := transform(r); err == nil {
+			return encoded
+		}
+	}
+
+	return []byte(formatDataForCurl(data))
+}
+
+// redaction returns the redactor of the client that created the request, or
+// the default one for requests created without a client.
+func (r *Request) redaction() *redactor {
+	if r.redactor == nil {
+		return defaultRedactor
+	}
+	return r.redactor
+}
+
+// Route returns the low-cardinality route of the request, such as "/users/{id}".
+// It is the path template when the request was built with PathParams, the path
+// normalized by Config.PathNormalizer otherwise, or empty if neither applies.
+func (r *Request) Route() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.route
+}
+
 // Method returns the HTTP method used for the request (e.g., "GET", "POST").
 func (r *Request) Method() string {
 	r.mu.RLock()
@@ -234,6 +275,8 @@ func (r *Request) toHTTPRequest(ctx context.Context) (*http.Request, error) {
 		return nil, err
 	}
 
+	r.body = httpReqData
+
 	newRequest = newRequest.WithContext(ctx)
 	r.rawReq = newRequest
 
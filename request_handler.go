@@ -19,16 +19,23 @@ func (h *requestHandler) handleRequestError(
 	req *Request,
 	method string,
 	startTime time.Time,
+	options *RequestOptions,
 	err error,
 ) (*Response, error) {
 	duration := time.Since(startTime)
 
 	if !h.vecto.logger.IsNoop() {
-		h.vecto.logger.Error(ctx, "http request failed", map[string]interface{}{
-			"url":    req.FullUrl(),
-			"method": method,
-			"error":  err.Error(),
-		})
+		h.vecto.logger.Error(ctx, "http request failed", h.vecto.requestLogFields(ctx, req, map[string]interface{}{
+			"method":      method,
+			"attempt":     requestStatsFromContext(ctx).attemptCount(),
+			"duration_ms": duration.Milliseconds(),
+			"error":       err.Error(),
+		}))
+	}
+
+	if res, ok := h.executeFallback(ctx, req, h.vecto.getFallback(options), err); ok {
+		h.vecto.completeCall(ctx, req, res, options, startTime, err)
+		return res, nil
 	}
 
 	h.vecto.recordMetrics(ctx, req, nil, duration, err)
@@ -41,17 +48,27 @@ func (h *requestHandler) handleCircuitBreakerError(
 	cbKey string,
 	breaker *CircuitBreaker,
 	startTime time.Time,
+	options *RequestOptions,
 	err error,
 ) (*Response, error) {
 	duration := time.Since(startTime)
 
 	if !h.vecto.logger.IsNoop() {
-		h.vecto.logger.Warn(ctx, "request blocked by circuit breaker", map[string]interface{}{
-			"url":    req.FullUrl(),
-			"method": req.Method(),
-			"key":    cbKey,
-			"state":  breaker.GetState().String(),
-		})
+		h.vecto.logger.Warn(ctx, "request blocked by circuit breaker", h.vecto.requestLogFields(ctx, req, map[string]interface{}{
+			"breaker_key":   cbKey,
+			"breaker_state": breaker.GetState().String(),
+			"duration_ms":   duration.Milliseconds(),
+		}))
+	}
+
+	addSpanEvent(ctx, "circuit_breaker.rejected", map[string]any{
+		"circuit_breaker.key":   cbKey,
+		"circuit_breaker.state": breaker.GetState().String(),
+	})
+
+	if res, ok := h.executeFallback(ctx, req, h.vecto.getFallback(options), err); ok {
+		h.vecto.completeCall(ctx, req, res, options, startTime, err)
+		return res, nil
 	}
 
 	h.vecto.recordMetrics(ctx, req, nil, duration, err)
@@ -67,11 +84,13 @@ func (h *requestHandler) getOrSetCircuitBreakerKey(req *Request) string {
 	}
 	req.mu.RUnlock()
 
+	key := h.vecto.getCircuitBreakerKey(req)
+
 	req.mu.Lock()
 	defer req.mu.Unlock()
 
 	if !req.cbKeyCached {
-		req.cbKey = h.vecto.getCircuitBreakerKey(req)
+		req.cbKey = key
 		req.cbKeyCached = true
 	}
 
@@ -87,6 +106,5 @@ func (h *requestHandler) executeRequest(
 	if retryConfig != nil && shouldUseRetry(breaker) {
 		return h.vecto.executeWithRetry(ctx, req, retryConfig)
 	}
-	return h.vecto.client.Do(ctx, req)
+	return h.vecto.doAttempt(ctx, req)
 }
-
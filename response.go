@@ -17,7 +17,20 @@ type Response struct {
 	RawRequest  *http.Request
 	RawResponse *http.Response
 	success     bool
+	fallback    bool
 	TraceInfo   *TraceInfo
+
+	// exhaustedAttempts is the number of attempts made when the retries ran
+	// out on a retryable response, zero otherwise.
+	exhaustedAttempts int
+
+	// Protocol is the protocol the response was received with, e.g. "HTTP/1.1" or "HTTP/2.0".
+	Protocol string
+
+	// AttemptTraces holds the trace of every attempt, in order, when tracing
+	// is enabled and the request has a retry configuration. The last entry is
+	// the trace of this response.
+	AttemptTraces []*TraceInfo
 }
 
 func (r *Response) deepCopy() *Response {
@@ -53,7 +66,7 @@ func (r *Response) deepCopy() *Response {
 			Request:          rawReqCopy,
 			TLS:              r.RawResponse.TLS,
 		}
-		
+
 		rawResCopy.Body = io.NopCloser(bytes.NewReader(dataCopy))
 	}
 
@@ -64,15 +77,28 @@ func (r *Response) deepCopy() *Response {
 		RawResponse: rawResCopy,
 		request:     r.request,
 		success:     r.success,
+		fallback:    r.fallback,
 		TraceInfo:   r.TraceInfo,
+		Protocol:    r.Protocol,
+
+		exhaustedAttempts: r.exhaustedAttempts,
+
+		AttemptTraces: cloneTraceSlice(r.AttemptTraces),
+	}
+}
+
+func cloneTraceSlice(traces []*TraceInfo) []*TraceInfo {
+	if traces == nil {
+		return nil
 	}
+	return append([]*TraceInfo(nil), traces...)
 }
 
 func cloneHTTPHeaders(headers http.Header) http.Header {
 	if headers == nil {
 		return nil
 	}
-	
+
 	result := make(http.Header, len(headers))
 	for k, v := range headers {
 		result[k] = cloneStringSlice(v)
@@ -84,7 +110,7 @@ func cloneStringSlice(s []string) []string {
 	if s == nil {
 		return nil
 	}
-	
+
 	result := make([]string, len(s))
 	copy(result, s)
 	return result
@@ -94,6 +120,12 @@ func (r *Response) Success() bool {
 	return r.success
 }
 
+// IsFallback reports whether the response was produced by a fallback
+// instead of being received from the server.
+func (r *Response) IsFallback() bool {
+	return r.fallback
+}
+
 func (r *Response) RequestFailedError() error {
 	if r.success {
 		return nil
@@ -1051,3 +1051,25 @@ func TestResponse_Integration(t *testing.T) {
 		}
 	})
 }
+
+func TestResponse_DeepCopy(t *testing.T) {
+	original := &Response{
+		Data:              []byte("body"),
+		StatusCode:        http.StatusServiceUnavailable,
+		fallback:          true,
+		exhaustedAttempts: 3,
+	}
+
+	copied := original.deepCopy()
+	copied.Data[0] = 'B'
+
+	if string(original.Data) != "body" {
+		t.Errorf("expected original data to be unchanged, got %q", original.Data)
+	}
+	if !copied.IsFallback() {
+		t.Error("expected copy to keep the fallback flag")
+	}
+	if copied.exhaustedAttempts != 3 {
+		t.Errorf("expected copy to keep exhausted attempts 3, got %d", copied.exhaustedAttempts)
+	}
+}
@@ -202,17 +202,27 @@ func (v *Vecto) executeWithRetry(
 	retryConfig *RetryConfig,
 ) (*Response, error) {
 	if retryConfig == nil || retryConfig.MaxAttempts == 0 {
-		return v.client.Do(ctx, req)
+		return v.doAttempt(ctx, req)
 	}
 
 	var lastResponse *Response
 	var lastErr error
 	attempt := 0
 
+	if v.config.EnableTrace {
+		var traces *attemptTraces
+		ctx, traces = withAttemptTraces(ctx)
+		defer func() {
+			if lastResponse != nil {
+				lastResponse.AttemptTraces = traces.list()
+			}
+		}()
+	}
+
 	for {
 		attempt++
 
-		res, err := v.client.Do(ctx, req)
+		res, err := v.doAttempt(ctx, req)
 		lastResponse = res
 		lastErr = err
 
@@ -234,13 +244,25 @@ func (v *Vecto) executeWithRetry(
 			retryConfig.OnRetry(attempt, err)
 		}
 
+		retryAttrs := map[string]any{
+			"http.request.resend_count": attempt,
+			"wait_time":                 waitTime.String(),
+		}
+		if res != nil {
+			retryAttrs["http.response.status_code"] = res.StatusCode
+		}
+		if err != nil {
+			retryAttrs["error.type"] = errorType(err)
+		}
+		addSpanEvent(ctx, "retry", retryAttrs)
+		v.recordRetry(ctx, req, attempt)
+
 		if !v.logger.IsNoop() {
-			v.logger.Warn(ctx, "retrying request", map[string]interface{}{
+			v.logger.Warn(ctx, "retrying request", v.requestLogFields(ctx, req, map[string]interface{}{
 				"attempt":   attempt,
 				"wait_time": waitTime.String(),
-				"url":       req.FullUrl(),
 				"error":     formatErrorForLog(err),
-			})
+			}))
 		}
 
 		select {
@@ -254,9 +276,54 @@ func (v *Vecto) executeWithRetry(
 		return lastResponse, fmt.Errorf("request failed after %d attempts: %w", attempt, lastErr)
 	}
 
+	if attempt > 1 && retriesExhausted(attempt, retryConfig, lastResponse) {
+		lastResponse.exhaustedAttempts = attempt
+	}
+
 	return lastResponse, nil
 }
 
+// retriesExhausted reports whether the retry loop stopped because the attempt
+// limit was reached while the last response was still considered retryable.
+// Callers only check it once a retry happened, so a single attempt never
+// counts as exhausted.
+func retriesExhausted(attempt int, config *RetryConfig, res *Response) bool {
+	if config == nil || res == nil {
+		return false
+	}
+
+	if config.MaxAttempts < 0 || attempt < config.MaxAttempts {
+		return false
+	}
+
+	condition := config.RetryCondition
+	if condition == nil {
+		condition = DefaultRetryCondition
+	}
+
+	return condition(res, nil)
+}
+
+// sendAttempt sends a single attempt of req through the Client, recording its
+// duration for the RequestMetrics and its entry in the HAR recorder.
+func (v *Vecto) sendAttempt(ctx context.Context, req *Request) (*Response, error) {
+	stats := requestStatsFromContext(ctx)
+	if stats == nil && v.config.HAR == nil {
+		return v.client.Do(ctx, req)
+	}
+
+	start := time.Now()
+	res, err := v.client.Do(ctx, req)
+	duration := time.Since(start)
+	stats.addAttempt(duration)
+
+	if v.config.HAR != nil {
+		v.config.HAR.record(ctx, req, res, err, start, duration, stats.attemptCount())
+	}
+
+	return res, err
+}
+
 // formatErrorForLog formats an error for logging.
 func formatErrorForLog(err error) string {
 	if err == nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -312,6 +313,11 @@ func TestIsNetworkError(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name:     "truncated body",
+			err:      fmt.Errorf("read body: %w", io.ErrUnexpectedEOF),
+			expected: false,
+		},
 		{
 			name:     "generic error",
 			err:      errors.New("generic error"),
@@ -0,0 +1,116 @@
+package vecto
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// PathNormalizer maps a request path to a low-cardinality route, such as
+// "/users/123" to "/users/{id}". It is used for requests that were not
+// built from a path template.
+type PathNormalizer func(path string) string
+
+// PathRule replaces every match of Pattern in a request path with Replacement.
+// Replacement may reference capture groups, as in regexp.Regexp.ReplaceAllString.
+type PathRule struct {
+	Pattern     string
+	Replacement string
+}
+
+// DefaultPathRules replace UUIDs with "{uuid}" and numeric path segments with "{id}".
+// Only whole segments are replaced, so dates and versions such as
+// "/2024-01-02" or "/v1.2" are kept.
+func DefaultPathRules() []PathRule {
+	// A match consumes the slash ending its segment, so the numeric rule runs
+	// twice to reach the segments directly following a replaced one.
+	numeric := PathRule{Pattern: `/[0-9]+(/|$)`, Replacement: "/{id}$1"}
+
+	return []PathRule{
+		{Pattern: `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`, Replacement: "{uuid}"},
+		numeric,
+		numeric,
+	}
+}
+
+// NewRegexPathNormalizer returns a PathNormalizer applying the rules in order.
+// It returns an error if a pattern does not compile.
+//
+// Example:
+//
+//	normalizer, err := vecto.NewRegexPathNormalizer(append(vecto.DefaultPathRules(),
+//	    vecto.PathRule{Pattern: `^/files/.+$`, Replacement: "/files/{name}"})...)
+func NewRegexPathNormalizer(rules ...PathRule) (PathNormalizer, error) {
+	type compiledRule struct {
+		pattern     *regexp.Regexp
+		replacement string
+	}
+
+	compiled := make([]compiledRule, len(rules))
+	for i, rule := range rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path rule %q: %w", rule.Pattern, err)
+		}
+		compiled[i] = compiledRule{pattern: pattern, replacement: rule.Replacement}
+	}
+
+	return func(path string) string {
+		for _, rule := range compiled {
+			path = rule.pattern.ReplaceAllString(path, rule.replacement)
+		}
+		return path
+	}, nil
+}
+
+// templateRoute returns the path of a URL template such as
+// "https://api.example.com/users/{id}".
+func templateRoute(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+// resolveRoute returns the route of a request built from urlTemplate: the
+// template path when path params are substituted, otherwise the path
+// normalized by the configured PathNormalizer, if any.
+func (v *Vecto) resolveRoute(urlTemplate string, pathParams map[string]string, req *Request) string {
+	if len(pathParams) > 0 {
+		return templateRoute(urlTemplate)
+	}
+
+	if v.config.PathNormalizer != nil {
+		return v.config.PathNormalizer(req.Path())
+	}
+
+	return ""
+}
+
+// metricsRoute returns the route of a request, falling back to its
+// normalized URL when it has none.
+func (v *Vecto) metricsRoute(req *Request) string {
+	if route := req.Route(); route != "" {
+		return route
+	}
+	return v.normalizeURL(req)
+}
+
+type routeKey struct{}
+
+func withRoute(ctx context.Context, route string) context.Context {
+	if route == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+// RouteFromContext returns the route of the request carrying ctx, such as
+// "/users/{id}". It is meant for TransportMiddleware: a round tripper
+// receives the request context but not the Request.
+func RouteFromContext(ctx context.Context) (string, bool) {
+	route, ok := ctx.Value(routeKey{}).(string)
+	return route, ok
+}
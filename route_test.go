@@ -0,0 +1,109 @@
+package vecto
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexPathNormalizer(t *testing.T) {
+	normalizer, err := NewRegexPathNormalizer(append(DefaultPathRules(),
+		PathRule{Pattern: `^/files/.+$`, Replacement: "/files/{name}"})...)
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"/users/123":          "/users/{id}",
+		"/users/1/orders/2":   "/users/{id}/orders/{id}",
+		"/1/2/3":              "/{id}/{id}/{id}",
+		"/v1/users":           "/v1/users",
+		"/users/123abc":       "/users/123abc",
+		"/1/2/3/4/":           "/{id}/{id}/{id}/{id}/",
+		"/reports/2024-01-02": "/reports/2024-01-02",
+		"/api/v1.2/items/7":   "/api/v1.2/items/{id}",
+		"/files.1/5.json":     "/files.1/5.json",
+		"/orders/3f2504e0-4f89-11d3-9a0c-0305e82c3301": "/orders/{uuid}",
+		"/files/a/b.txt": "/files/{name}",
+	}
+	for path, expected := range tests {
+		assert.Equal(t, expected, normalizer(path), path)
+	}
+
+	_, err = NewRegexPathNormalizer(PathRule{Pattern: "("})
+	assert.Error(t, err)
+}
+
+func TestRequestRoute(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	t.Run("uses the path template", func(t *testing.T) {
+		collector := &mockMetricsCollector{}
+		logger := &mockLogger{}
+		tracer := &mockTracer{}
+
+		v, err := New(Config{
+			BaseURL:          srv.URL + "/api",
+			MetricsCollector: collector,
+			Logger:           logger,
+			Tracing:          &TracingConfig{Tracer: tracer},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/users/{id}", &RequestOptions{
+			PathParams: map[string]string{"id": "42"},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "/api/users/{id}", res.request.Route())
+		assert.Equal(t, "/api/users/42", res.request.Path())
+
+		require.Len(t, collector.requests, 1)
+		assert.Equal(t, "/api/users/{id}", collector.requests[0].Route)
+		assert.Equal(t, srv.URL+"/api/users/42", collector.requests[0].URL)
+
+		require.NotEmpty(t, logger.infoCalls)
+		assert.Equal(t, "/api/users/{id}", logger.infoCalls[len(logger.infoCalls)-1].fields["route"])
+
+		span := tracer.lastSpan()
+		require.NotNil(t, span)
+		assert.Equal(t, "GET /api/users/{id}", span.name)
+		assert.Equal(t, "/api/users/{id}", span.attrs["http.route"])
+	})
+
+	t.Run("uses the path normalizer", func(t *testing.T) {
+		collector := NewPrometheusCollector(PrometheusConfig{})
+		normalizer, err := NewRegexPathNormalizer(DefaultPathRules()...)
+		require.NoError(t, err)
+
+		v, err := New(Config{
+			BaseURL:          srv.URL,
+			MetricsCollector: collector,
+			PathNormalizer:   normalizer,
+		})
+		require.NoError(t, err)
+
+		for _, path := range []string{"/users/1", "/users/2"} {
+			res, err := v.Get(context.Background(), path, nil)
+			require.NoError(t, err)
+			assert.Equal(t, "/users/{id}", res.request.Route())
+		}
+
+		assert.Contains(t, scrape(t, collector), `vecto_http_client_requests_total{method="GET",route="/users/{id}",status_class="2xx"} 2`)
+	})
+
+	t.Run("is empty without a template or normalizer", func(t *testing.T) {
+		tracer := &mockTracer{}
+		v, err := New(Config{BaseURL: srv.URL, Tracing: &TracingConfig{Tracer: tracer}})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/users/1", nil)
+		require.NoError(t, err)
+		assert.Empty(t, res.request.Route())
+		assert.Equal(t, "GET", tracer.lastSpan().name)
+		assert.NotContains(t, tracer.lastSpan().attrs, "http.route")
+	})
+}
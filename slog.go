@@ -0,0 +1,164 @@
+package vecto
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// slogLogger adapts a *slog.Logger to Logger.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing to logger, or to slog.Default() if
+// logger is nil. Fields become attributes, sorted by key.
+//
+// Example:
+//
+//	client, _ := vecto.New(vecto.Config{
+//	    Logger:   vecto.NewSlogLogger(slog.New(slog.NewJSONHandler(os.Stderr, nil))),
+//	    LogLevel: vecto.LogLevelInfo,
+//	})
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &slogLogger{logger: logger}
+}
+
+func (l *slogLogger) Debug(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.log(ctx, slog.LevelDebug, msg, fields)
+}
+
+func (l *slogLogger) Info(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.log(ctx, slog.LevelInfo, msg, fields)
+}
+
+func (l *slogLogger) Warn(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.log(ctx, slog.LevelWarn, msg, fields)
+}
+
+func (l *slogLogger) Error(ctx context.Context, msg string, fields map[string]interface{}) {
+	l.log(ctx, slog.LevelError, msg, fields)
+}
+
+func (l *slogLogger) IsNoop() bool {
+	return false
+}
+
+func (l *slogLogger) log(ctx context.Context, level slog.Level, msg string, fields map[string]interface{}) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, len(keys))
+	for i, k := range keys {
+		attrs[i] = slog.Any(k, fields[k])
+	}
+
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// slogHandler adapts a Logger to slog.Handler.
+type slogHandler struct {
+	logger Logger
+	attrs  map[string]interface{}
+	prefix string
+}
+
+// NewSlogHandler returns a slog.Handler writing to logger, so code logging
+// with slog can share a vecto Logger. Attributes in groups are flattened to
+// "group.key" fields, and levels map to the nearest Logger method at or below them.
+func NewSlogHandler(logger Logger) slog.Handler {
+	if logger == nil {
+		logger = newNoopLogger()
+	}
+	return &slogHandler{logger: logger}
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return !h.logger.IsNoop()
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	fields := make(map[string]interface{}, len(h.attrs)+record.NumAttrs())
+	for k, v := range h.attrs {
+		fields[k] = v
+	}
+
+	record.Attrs(func(attr slog.Attr) bool {
+		addSlogAttr(fields, h.prefix, attr)
+		return true
+	})
+
+	switch {
+	case record.Level < slog.LevelInfo:
+		h.logger.Debug(ctx, record.Message, fields)
+	case record.Level < slog.LevelWarn:
+		h.logger.Info(ctx, record.Message, fields)
+	case record.Level < slog.LevelError:
+		h.logger.Warn(ctx, record.Message, fields)
+	default:
+		h.logger.Error(ctx, record.Message, fields)
+	}
+
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := h.clone()
+	for _, attr := range attrs {
+		addSlogAttr(clone.attrs, h.prefix, attr)
+	}
+	return clone
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := h.clone()
+	clone.prefix = h.prefix + name + "."
+	return clone
+}
+
+func (h *slogHandler) clone() *slogHandler {
+	attrs := make(map[string]interface{}, len(h.attrs))
+	for k, v := range h.attrs {
+		attrs[k] = v
+	}
+	return &slogHandler{logger: h.logger, attrs: attrs, prefix: h.prefix}
+}
+
+// addSlogAttr adds attr to fields under prefix, flattening groups.
+func addSlogAttr(fields map[string]interface{}, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+
+	if value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = prefix + attr.Key + "."
+		}
+		for _, member := range value.Group() {
+			addSlogAttr(fields, groupPrefix, member)
+		}
+		return
+	}
+
+	if attr.Key == "" {
+		return
+	}
+
+	fields[prefix+attr.Key] = value.Any()
+}
@@ -0,0 +1,123 @@
+package vecto
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeJSONLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	logger.Debug(context.Background(), "hidden", nil)
+	logger.Warn(context.Background(), "retrying request", map[string]interface{}{"attempt": 2, "request_id": "abc"})
+	assert.False(t, logger.IsNoop())
+
+	entries := decodeJSONLines(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "WARN", entries[0]["level"])
+	assert.Equal(t, "retrying request", entries[0]["msg"])
+	assert.Equal(t, float64(2), entries[0]["attempt"])
+	assert.Equal(t, "abc", entries[0]["request_id"])
+}
+
+func TestSlogHandler(t *testing.T) {
+	logger := &mockLogger{}
+	sl := slog.New(NewSlogHandler(logger)).With("service", "api").WithGroup("http")
+
+	sl.Debug("debug")
+	sl.Info("info", "status", 200, slog.Group("req", "method", "GET"))
+	sl.Warn("warn")
+	sl.Error("error", "err", "boom")
+
+	require.Len(t, logger.debugCalls, 1)
+	require.Len(t, logger.infoCalls, 1)
+	require.Len(t, logger.warnCalls, 1)
+	require.Len(t, logger.errorCalls, 1)
+
+	fields := logger.infoCalls[0].fields
+	assert.Equal(t, "api", fields["service"])
+	assert.Equal(t, int64(200), fields["http.status"])
+	assert.Equal(t, "GET", fields["http.req.method"])
+	assert.Equal(t, "boom", logger.errorCalls[0].fields["http.err"])
+
+	assert.False(t, NewSlogHandler(nil).Enabled(context.Background(), slog.LevelError))
+}
+
+func TestLogLevel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	logger := &mockLogger{}
+	v, err := New(Config{BaseURL: srv.URL, Logger: logger, LogLevel: LogLevelWarn})
+	require.NoError(t, err)
+
+	_, err = v.Get(context.Background(), "/", nil)
+	require.NoError(t, err)
+	assert.Empty(t, logger.debugCalls)
+	assert.Empty(t, logger.infoCalls)
+
+	_, err = New(Config{LogLevel: LogLevelError + 1})
+	assert.Error(t, err)
+}
+
+func TestRequestLogFields(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	logger := &mockLogger{}
+	cbConfig := DefaultCircuitBreakerConfig()
+	cbConfig.FailureThreshold = 1
+	v, err := New(Config{BaseURL: srv.URL, Logger: logger, CircuitBreaker: &cbConfig})
+	require.NoError(t, err)
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	_, err = v.Get(ctx, "/", nil)
+	require.NoError(t, err)
+
+	require.Len(t, logger.infoCalls, 2)
+	completed := logger.infoCalls[0].fields
+	assert.Equal(t, "req-1", completed["request_id"])
+	assert.Equal(t, 1, completed["attempt"])
+	assert.Contains(t, completed, "duration_ms")
+	assert.Equal(t, srv.URL, completed["breaker_key"])
+	assert.Equal(t, srv.URL, logger.infoCalls[1].fields["breaker_key"])
+
+	_, err = v.Get(context.Background(), "/", nil)
+	require.Error(t, err)
+
+	require.Len(t, logger.warnCalls, 1)
+	blocked := logger.warnCalls[0].fields
+	assert.Equal(t, "request blocked by circuit breaker", logger.warnCalls[0].msg)
+	assert.NotEmpty(t, blocked["request_id"])
+	assert.NotEqual(t, "req-1", blocked["request_id"])
+	assert.Equal(t, srv.URL, blocked["breaker_key"])
+	assert.Equal(t, "open", blocked["breaker_state"])
+	assert.Equal(t, "GET", blocked["method"])
+}
@@ -0,0 +1,312 @@
+package vecto
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+)
+
+// TLSConfig configures how the client verifies servers and authenticates itself.
+// Trusted CAs and client certificates are configured independently, so a private
+// CA can be trusted without presenting a client certificate.
+type TLSConfig struct {
+	// RootCAs lists the CA certificates used to verify servers.
+	// When empty, the system pool is used.
+	RootCAs []CABundle
+
+	// AppendSystemRoots adds RootCAs to the system pool instead of replacing it.
+	AppendSystemRoots bool
+
+	// ClientCertificates lists the key pairs presented for mutual TLS.
+	ClientCertificates []ClientCertificate
+
+	// ServerName overrides the name used for SNI and certificate verification.
+	ServerName string
+
+	// MinVersion is the minimum TLS version accepted (e.g. tls.VersionTLS12).
+	// Default: the crypto/tls default.
+	MinVersion uint16
+
+	// CipherSuites restricts the cipher suites offered for TLS 1.0-1.2.
+	CipherSuites []uint16
+
+	// NextProtos lists the ALPN protocols offered, in order of preference.
+	NextProtos []string
+
+	// Pins maps host names to the SPKI pins accepted for them. Hosts may use a
+	// leading wildcard label such as "*.example.com" and are matched against the
+	// TLS server name, or against the dialed IP address when there is none.
+	// Pins are checked after the chain has been verified against the configured CAs,
+	// and only against verified chains: pinned hosts are rejected when
+	// InsecureSkipVerify disables the verification.
+	Pins map[string]PinSet
+
+	// ReloadInterval enables hot reloading of file-based CA bundles and client
+	// certificates. Files are checked for changes at most once per interval during
+	// TLS handshakes. If a reload fails, the error is logged and the previously
+	// loaded material stays in use.
+	// Default: 0 (files are loaded once)
+	ReloadInterval time.Duration
+}
+
+// CABundle is a set of PEM-encoded CA certificates, given inline or as a file path.
+type CABundle struct {
+	PEM  string
+	File string
+}
+
+// ClientCertificate is a PEM-encoded certificate and private key pair,
+// given inline or as file paths.
+type ClientCertificate struct {
+	Cert     string
+	Key      string
+	CertFile string
+	KeyFile  string
+}
+
+func (b CABundle) describe() string {
+	if b.File != "" {
+		return fmt.Sprintf("file %q", b.File)
+	}
+	return "inline PEM"
+}
+
+func (b CABundle) load() ([]byte, error) {
+	if b.File != "" {
+		data, err := os.ReadFile(b.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", b.describe(), err)
+		}
+		return data, nil
+	}
+	return []byte(b.PEM), nil
+}
+
+func (c ClientCertificate) describe() string {
+	if c.CertFile != "" {
+		return fmt.Sprintf("file %q", c.CertFile)
+	}
+	return "inline PEM"
+}
+
+func (c ClientCertificate) load() (tls.Certificate, error) {
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return cert, fmt.Errorf("failed to load X509 key pair from %s: %w", c.describe(), err)
+		}
+		return cert, nil
+	}
+
+	cert, err := tls.X509KeyPair([]byte(c.Cert), []byte(c.Key))
+	if err != nil {
+		return cert, fmt.Errorf("failed to load X509 key pair from %s: %w", c.describe(), err)
+	}
+	return cert, nil
+}
+
+func validateTLSConfig(config *TLSConfig) error {
+	if config == nil {
+		return nil
+	}
+
+	for i, ca := range config.RootCAs {
+		if ca.PEM == "" && ca.File == "" {
+			return fmt.Errorf("root CA %d: PEM or File is required", i)
+		}
+		if ca.PEM != "" && ca.File != "" {
+			return fmt.Errorf("root CA %d: PEM and File are mutually exclusive", i)
+		}
+	}
+
+	for i, cert := range config.ClientCertificates {
+		inline := cert.Cert != "" || cert.Key != ""
+		file := cert.CertFile != "" || cert.KeyFile != ""
+
+		switch {
+		case inline && file:
+			return fmt.Errorf("client certificate %d: inline PEM and files are mutually exclusive", i)
+		case inline && (cert.Cert == "" || cert.Key == ""):
+			return fmt.Errorf("client certificate %d: both Cert and Key are required", i)
+		case file && (cert.CertFile == "" || cert.KeyFile == ""):
+			return fmt.Errorf("client certificate %d: both CertFile and KeyFile are required", i)
+		case !inline && !file:
+			return fmt.Errorf("client certificate %d: Cert and Key or CertFile and KeyFile are required", i)
+		}
+	}
+
+	switch config.MinVersion {
+	case 0, tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13:
+	default:
+		return fmt.Errorf("unsupported minimum TLS version: 0x%04x", config.MinVersion)
+	}
+
+	for i, id := range config.CipherSuites {
+		if !isKnownCipherSuite(id) {
+			return fmt.Errorf("cipher suite %d: unsupported cipher suite 0x%04x", i, id)
+		}
+	}
+
+	for i, proto := range config.NextProtos {
+		if proto == "" {
+			return fmt.Errorf("ALPN protocol %d: cannot be empty", i)
+		}
+	}
+
+	if config.ReloadInterval < 0 {
+		return fmt.Errorf("reload interval cannot be negative")
+	}
+
+	if err := validatePins(config.Pins); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// hasFiles reports whether any CA bundle or client certificate is loaded from disk.
+func (c *TLSConfig) hasFiles() bool {
+	for _, ca := range c.RootCAs {
+		if ca.File != "" {
+			return true
+		}
+	}
+	for _, cert := range c.ClientCertificates {
+		if cert.CertFile != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func isKnownCipherSuite(id uint16) bool {
+	for _, suite := range tls.CipherSuites() {
+		if suite.ID == id {
+			return true
+		}
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTLSConfig loads the CA bundles and client certificates and applies the
+// TLS policy on top of tlsConfig. When hot reloading is enabled, the material is
+// served through certificate and verification callbacks instead. When the
+// server verification depends on the dialed host, for the reloaded CAs or for
+// pins, the function verifying it is returned.
+func applyTLSConfig(tlsConfig *tls.Config, config *TLSConfig, logger Logger) (hostVerifyFunc, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	var reloader *certReloader
+	if config.ReloadInterval > 0 && config.hasFiles() {
+		var err error
+		reloader, err = newCertReloader(config, tlsConfig.RootCAs, tlsConfig.Certificates, logger)
+		if err != nil {
+			return nil, err
+		}
+		reloader.install(tlsConfig)
+	} else {
+		if len(config.RootCAs) > 0 {
+			pool, err := loadRootCAs(tlsConfig.RootCAs, config)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		certs, err := loadClientCertificates(config)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, certs...)
+	}
+
+	if config.ServerName != "" {
+		tlsConfig.ServerName = config.ServerName
+	}
+
+	if config.MinVersion != 0 {
+		tlsConfig.MinVersion = config.MinVersion
+	}
+
+	if len(config.CipherSuites) > 0 {
+		tlsConfig.CipherSuites = append([]uint16(nil), config.CipherSuites...)
+	}
+
+	if len(config.NextProtos) > 0 {
+		tlsConfig.NextProtos = append([]string(nil), config.NextProtos...)
+	}
+
+	var verify hostVerifyFunc
+	if reloader != nil && reloader.verifiesServer {
+		verify = reloader.verifyConnection
+	}
+
+	if len(config.Pins) > 0 {
+		verifier, err := newPinVerifier(config.Pins, logger)
+		if err != nil {
+			return nil, err
+		}
+		if verify != nil {
+			// The reloader verifies the chains itself and hands them over.
+			reloader.pins = verifier
+		} else {
+			verifier.install(tlsConfig)
+			verify = verifier.verifyConnection
+		}
+	}
+
+	return verify, nil
+}
+
+func loadClientCertificates(config *TLSConfig) ([]tls.Certificate, error) {
+	certs := make([]tls.Certificate, 0, len(config.ClientCertificates))
+	for i, certConfig := range config.ClientCertificates {
+		cert, err := certConfig.load()
+		if err != nil {
+			return nil, fmt.Errorf("client certificate %d: %w", i, err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// loadRootCAs builds the pool used to verify servers. Certificates are added to
+// the existing pool when there is one, or to the system pool when requested.
+func loadRootCAs(existing *x509.CertPool, config *TLSConfig) (*x509.CertPool, error) {
+	var pool *x509.CertPool
+
+	switch {
+	case existing != nil:
+		pool = existing.Clone()
+	case config.AppendSystemRoots:
+		systemPool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load system cert pool: %w", err)
+		}
+		pool = systemPool
+	default:
+		pool = x509.NewCertPool()
+	}
+
+	for i, ca := range config.RootCAs {
+		data, err := ca.load()
+		if err != nil {
+			return nil, fmt.Errorf("root CA %d: %w", i, err)
+		}
+
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("root CA %d: no valid PEM certificates found in %s", i, ca.describe())
+		}
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,216 @@
+package vecto
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const spkiPinPrefix = "sha256/"
+
+// PinSet is the set of SHA-256 SPKI pins accepted for a host.
+// Pins are base64-encoded hashes of the certificate's SubjectPublicKeyInfo,
+// optionally prefixed with "sha256/". A connection is accepted when any
+// certificate in the server chain matches one of the pins or backup pins.
+type PinSet struct {
+	// Pins are the hashes of the keys currently in use.
+	Pins []string
+
+	// BackupPins are the hashes of keys held in reserve for rotation.
+	BackupPins []string
+
+	// ReportOnly logs pin violations through the Logger without failing the connection.
+	ReportOnly bool
+}
+
+// SPKIPin returns the pin for the certificate's public key in "sha256/<base64>" form.
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return spkiPinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func validatePins(pins map[string]PinSet) error {
+	for host, set := range pins {
+		if host == "" {
+			return fmt.Errorf("pinned host cannot be empty")
+		}
+
+		if len(set.Pins)+len(set.BackupPins) == 0 {
+			return fmt.Errorf("pins for host %q: at least one pin is required", host)
+		}
+
+		for i, pin := range set.Pins {
+			if _, err := decodeSPKIPin(pin); err != nil {
+				return fmt.Errorf("pins for host %q: pin %d: %w", host, i, err)
+			}
+		}
+
+		for i, pin := range set.BackupPins {
+			if _, err := decodeSPKIPin(pin); err != nil {
+				return fmt.Errorf("pins for host %q: backup pin %d: %w", host, i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func decodeSPKIPin(pin string) ([sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, spkiPinPrefix))
+	if err != nil {
+		return hash, fmt.Errorf("invalid base64: %w", err)
+	}
+
+	if len(decoded) != sha256.Size {
+		return hash, fmt.Errorf("expected %d byte SHA-256 hash, got %d bytes", sha256.Size, len(decoded))
+	}
+
+	copy(hash[:], decoded)
+	return hash, nil
+}
+
+// pinSetEntry is a PinSet with decoded hashes.
+type pinSetEntry struct {
+	hashes     map[[sha256.Size]byte]struct{}
+	reportOnly bool
+}
+
+// pinVerifier checks server chains against the configured SPKI pins.
+type pinVerifier struct {
+	hosts  map[string]pinSetEntry
+	logger Logger
+
+	// pinsIPs is set when pins are keyed by IP address, which cannot be
+	// checked without the dialed host.
+	pinsIPs bool
+
+	// previousVerify is the VerifyConnection replaced by install.
+	previousVerify func(tls.ConnectionState) error
+}
+
+func newPinVerifier(pins map[string]PinSet, logger Logger) (*pinVerifier, error) {
+	if logger == nil {
+		logger = newNoopLogger()
+	}
+
+	verifier := &pinVerifier{
+		hosts:  make(map[string]pinSetEntry, len(pins)),
+		logger: logger,
+	}
+
+	for host, set := range pins {
+		entry := pinSetEntry{
+			hashes:     make(map[[sha256.Size]byte]struct{}, len(set.Pins)+len(set.BackupPins)),
+			reportOnly: set.ReportOnly,
+		}
+
+		for _, pin := range append(append([]string(nil), set.Pins...), set.BackupPins...) {
+			hash, err := decodeSPKIPin(pin)
+			if err != nil {
+				return nil, fmt.Errorf("pins for host %q: %w", host, err)
+			}
+			entry.hashes[hash] = struct{}{}
+		}
+
+		verifier.hosts[strings.ToLower(host)] = entry
+		if net.ParseIP(host) != nil {
+			verifier.pinsIPs = true
+		}
+	}
+
+	return verifier, nil
+}
+
+// install chains pin verification after any existing connection verification,
+// so pins are only checked for chains that passed CA verification.
+func (p *pinVerifier) install(tlsConfig *tls.Config) {
+	p.previousVerify = tlsConfig.VerifyConnection
+	tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		return p.verifyConnection(cs, "")
+	}
+}
+
+// verifyConnection runs the VerifyConnection replaced by install, then checks
+// the pins of the server name, which defaults to host, the host that was
+// dialed, if any. See installTLSDialer for why the host is needed.
+func (p *pinVerifier) verifyConnection(cs tls.ConnectionState, host string) error {
+	if p.previousVerify != nil {
+		if err := p.previousVerify(cs); err != nil {
+			return err
+		}
+	}
+	return p.verifyChains(serverNameOf(cs, host), cs.VerifiedChains, cs.PeerCertificates)
+}
+
+// lookup returns the pins for host. Exact matches take precedence over
+// wildcard entries such as "*.example.com", which match a single label.
+func (p *pinVerifier) lookup(host string) (pinSetEntry, bool) {
+	host = strings.ToLower(host)
+	if entry, ok := p.hosts[host]; ok {
+		return entry, true
+	}
+
+	if idx := strings.IndexByte(host, '.'); idx > 0 {
+		if entry, ok := p.hosts["*"+host[idx:]]; ok {
+			return entry, true
+		}
+	}
+
+	return pinSetEntry{}, false
+}
+
+// verifyChains checks chains, verified for serverName, against its pins.
+// presented are the certificates sent by the server, for logging. Only
+// verified chains are checked, so without them, such as when
+// InsecureSkipVerify is set, pinned hosts fail validation.
+func (p *pinVerifier) verifyChains(serverName string, chains [][]*x509.Certificate, presented []*x509.Certificate) error {
+	// Without a server name the target may be a pinned IP address, so fail
+	// closed rather than skip its pins.
+	if serverName == "" && p.pinsIPs {
+		return fmt.Errorf("tls: cannot check certificate pins without the dialed host")
+	}
+
+	entry, ok := p.lookup(serverName)
+	if !ok {
+		return nil
+	}
+
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if _, ok := entry.hashes[sha256.Sum256(cert.RawSubjectPublicKeyInfo)]; ok {
+				return nil
+			}
+		}
+	}
+
+	if !p.logger.IsNoop() {
+		presentedPins := make([]string, 0, len(presented))
+		for _, cert := range presented {
+			presentedPins = append(presentedPins, SPKIPin(cert))
+		}
+
+		fields := map[string]interface{}{
+			"host":        serverName,
+			"report_only": entry.reportOnly,
+			"presented":   strings.Join(presentedPins, ","),
+		}
+		if entry.reportOnly {
+			p.logger.Warn(context.Background(), "certificate pin validation failed", fields)
+		} else {
+			p.logger.Error(context.Background(), "certificate pin validation failed", fields)
+		}
+	}
+
+	if entry.reportOnly {
+		return nil
+	}
+
+	return fmt.Errorf("tls: certificate pin validation failed for host %q", serverName)
+}
@@ -0,0 +1,328 @@
+package vecto
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakePin(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestValidatePins(t *testing.T) {
+	tests := []struct {
+		name    string
+		pins    map[string]PinSet
+		wantErr string
+	}{
+		{
+			name: "valid pins",
+			pins: map[string]PinSet{"api.example.com": {Pins: []string{fakePin("a")}, BackupPins: []string{fakePin("b")}}},
+		},
+		{
+			name:    "empty pin set",
+			pins:    map[string]PinSet{"api.example.com": {}},
+			wantErr: `pins for host "api.example.com": at least one pin is required`,
+		},
+		{
+			name:    "invalid base64",
+			pins:    map[string]PinSet{"api.example.com": {Pins: []string{"sha256/%%%"}}},
+			wantErr: `pins for host "api.example.com": pin 0: invalid base64`,
+		},
+		{
+			name:    "wrong hash length",
+			pins:    map[string]PinSet{"api.example.com": {Pins: []string{fakePin("a")}, BackupPins: []string{"c2hvcnQ="}}},
+			wantErr: `pins for host "api.example.com": backup pin 0: expected 32 byte SHA-256 hash`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePins(tt.pins)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestPinVerifierLookup(t *testing.T) {
+	verifier, err := newPinVerifier(map[string]PinSet{
+		"api.example.com": {Pins: []string{fakePin("exact")}},
+		"*.example.com":   {Pins: []string{fakePin("wildcard")}, ReportOnly: true},
+	}, nil)
+	require.NoError(t, err)
+
+	entry, ok := verifier.lookup("API.example.com")
+	assert.True(t, ok)
+	assert.False(t, entry.reportOnly)
+
+	entry, ok = verifier.lookup("payments.example.com")
+	assert.True(t, ok)
+	assert.True(t, entry.reportOnly)
+
+	_, ok = verifier.lookup("a.b.example.com")
+	assert.False(t, ok)
+
+	_, ok = verifier.lookup("example.org")
+	assert.False(t, ok)
+}
+
+func TestCertificatePinning(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	host := "example.com"
+	serverPin := SPKIPin(srv.Certificate())
+
+	newClient := func(t *testing.T, pins PinSet, logger Logger) *Vecto {
+		v, err := New(Config{
+			BaseURL:       srv.URL,
+			Logger:        logger,
+			HTTPTransport: &http.Transport{DisableKeepAlives: true},
+			TLS: &TLSConfig{
+				RootCAs:    []CABundle{{PEM: serverCertificatePEM(srv)}},
+				ServerName: host,
+				Pins:       map[string]PinSet{host: pins},
+			},
+		})
+		require.NoError(t, err)
+		return v
+	}
+
+	t.Run("accepts matching pin", func(t *testing.T) {
+		v := newClient(t, PinSet{Pins: []string{serverPin}}, nil)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("accepts matching backup pin", func(t *testing.T) {
+		v := newClient(t, PinSet{
+			Pins:       []string{fakePin("retired")},
+			BackupPins: []string{strings.TrimPrefix(serverPin, "sha256/")},
+		}, nil)
+
+		_, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("rejects mismatched pin", func(t *testing.T) {
+		logger := &mockLogger{}
+		v := newClient(t, PinSet{Pins: []string{fakePin("other")}}, logger)
+
+		_, err := v.Get(context.Background(), "/", nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "certificate pin validation failed")
+		require.NotEmpty(t, logger.errorCalls)
+	})
+
+	t.Run("report only mode logs without failing", func(t *testing.T) {
+		logger := &mockLogger{}
+		v := newClient(t, PinSet{Pins: []string{fakePin("other")}, ReportOnly: true}, logger)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		found := false
+		for _, call := range logger.warnCalls {
+			if call.msg == "certificate pin validation failed" {
+				found = true
+				assert.Equal(t, host, call.fields["host"])
+				assert.Equal(t, serverPin, call.fields["presented"])
+			}
+		}
+		assert.True(t, found, "expected pin violation to be logged")
+	})
+
+	t.Run("CA verification still applies", func(t *testing.T) {
+		otherCA, _ := generateTestCertificate(t, "other-ca")
+
+		v, err := New(Config{
+			BaseURL: srv.URL,
+			TLS: &TLSConfig{
+				RootCAs:    []CABundle{{PEM: string(otherCA)}},
+				ServerName: host,
+				Pins:       map[string]PinSet{host: {Pins: []string{serverPin}}},
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = v.Get(context.Background(), "/", nil)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "certificate pin validation failed")
+	})
+}
+
+func TestCertificatePinningOnlyMatchesVerifiedChains(t *testing.T) {
+	leafPEM, leafKeyPEM := generateTestCertificate(t, "example.com")
+	extraPEM, _ := generateTestCertificate(t, "attacker")
+
+	pair, err := tls.X509KeyPair(leafPEM, leafKeyPEM)
+	require.NoError(t, err)
+	extraBlock, _ := pem.Decode(extraPEM)
+	pair.Certificate = append(pair.Certificate, extraBlock.Bytes)
+
+	extraCert, err := x509.ParseCertificate(extraBlock.Bytes)
+	require.NoError(t, err)
+	leafCert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{pair}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		tls  TLSConfig
+		pin  string
+	}{
+		{
+			name: "unverified extra certificate",
+			tls:  TLSConfig{RootCAs: []CABundle{{PEM: string(leafPEM)}}},
+			pin:  SPKIPin(extraCert),
+		},
+		{
+			name: "unverified extra certificate with reloading",
+			tls:  TLSConfig{RootCAs: []CABundle{{File: writeTempFile(t, leafPEM)}}, ReloadInterval: time.Millisecond},
+			pin:  SPKIPin(extraCert),
+		},
+		{
+			name: "verification disabled",
+			pin:  SPKIPin(leafCert),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig := tt.tls
+			tlsConfig.ServerName = "example.com"
+			tlsConfig.Pins = map[string]PinSet{"example.com": {Pins: []string{tt.pin}}}
+
+			v, err := New(Config{
+				BaseURL:            srv.URL,
+				InsecureSkipVerify: len(tt.tls.RootCAs) == 0,
+				TLS:                &tlsConfig,
+			})
+			require.NoError(t, err)
+
+			_, err = v.Get(context.Background(), "/", nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "certificate pin validation failed")
+		})
+	}
+}
+
+func TestCertificatePinningIPTarget(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	certPEM := serverCertificatePEM(srv)
+	require.True(t, strings.HasPrefix(srv.URL, "https://127.0.0.1:"))
+
+	tests := []struct {
+		name    string
+		tls     TLSConfig
+		pin     string
+		proxied bool
+		wantErr bool
+	}{
+		{
+			name: "accepts matching pin",
+			tls:  TLSConfig{RootCAs: []CABundle{{PEM: certPEM}}},
+			pin:  SPKIPin(srv.Certificate()),
+		},
+		{
+			name:    "rejects mismatched pin",
+			tls:     TLSConfig{RootCAs: []CABundle{{PEM: certPEM}}},
+			pin:     fakePin("other"),
+			wantErr: true,
+		},
+		{
+			name:    "accepts matching pin through a proxy",
+			tls:     TLSConfig{RootCAs: []CABundle{{PEM: certPEM}}},
+			pin:     SPKIPin(srv.Certificate()),
+			proxied: true,
+		},
+		{
+			name:    "rejects mismatched pin through a proxy",
+			tls:     TLSConfig{RootCAs: []CABundle{{PEM: certPEM}}},
+			pin:     fakePin("other"),
+			proxied: true,
+			wantErr: true,
+		},
+		{
+			name:    "rejects mismatched pin with reloading",
+			tls:     TLSConfig{RootCAs: []CABundle{{File: writeTempFile(t, []byte(certPEM))}}, ReloadInterval: time.Millisecond},
+			pin:     fakePin("other"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig := tt.tls
+			tlsConfig.Pins = map[string]PinSet{"127.0.0.1": {Pins: []string{tt.pin}}}
+
+			config := Config{BaseURL: srv.URL, TLS: &tlsConfig}
+			if tt.proxied {
+				config.Proxy = &ProxyConfig{URL: newTestHTTPProxy(t).URL}
+			}
+
+			v, err := New(config)
+			require.NoError(t, err)
+
+			_, err = v.Get(context.Background(), "/", nil)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), `certificate pin validation failed for host "127.0.0.1"`)
+		})
+	}
+}
+
+func TestPinVerifierRequiresHostForIPPins(t *testing.T) {
+	verifier, err := newPinVerifier(map[string]PinSet{"127.0.0.1": {Pins: []string{fakePin("ip")}}}, nil)
+	require.NoError(t, err)
+
+	err = verifier.verifyConnection(tls.ConnectionState{}, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot check certificate pins without the dialed host")
+
+	verifier, err = newPinVerifier(map[string]PinSet{"example.com": {Pins: []string{fakePin("name")}}}, nil)
+	require.NoError(t, err)
+	assert.NoError(t, verifier.verifyConnection(tls.ConnectionState{}, ""), "hosts without pins are not checked")
+}
+
+func writeTempFile(t *testing.T, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "file.pem")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
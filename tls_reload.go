@@ -0,0 +1,360 @@
+package vecto
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileStamp identifies a version of a file on disk.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// certReloader serves CA bundles and client certificates that are reloaded from
+// disk when their files change. The previous material is kept when a reload fails.
+type certReloader struct {
+	mu          sync.Mutex
+	config      *TLSConfig
+	logger      Logger
+	basePool    *x509.CertPool
+	staticCerts []tls.Certificate
+	rootCAs     *x509.CertPool
+	certs       []tls.Certificate
+	stamps      map[string]fileStamp
+	lastCheck   time.Time
+
+	// verifiesServer is set when the reloader took over server verification.
+	// previousVerify is the VerifyConnection it replaced and pins checks the
+	// chains it verified.
+	verifiesServer bool
+	previousVerify func(tls.ConnectionState) error
+	pins           *pinVerifier
+}
+
+func newCertReloader(config *TLSConfig, basePool *x509.CertPool, staticCerts []tls.Certificate, logger Logger) (*certReloader, error) {
+	if logger == nil {
+		logger = newNoopLogger()
+	}
+
+	r := &certReloader{
+		config:      config,
+		logger:      logger,
+		basePool:    basePool,
+		staticCerts: staticCerts,
+	}
+
+	stamps, err := r.statFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	r.stamps = stamps
+	r.lastCheck = time.Now()
+
+	return r, nil
+}
+
+// install wires the reloader into tlsConfig. Server verification is performed in
+// VerifyConnection so that reloaded CA bundles apply to new connections.
+func (r *certReloader) install(tlsConfig *tls.Config) {
+	if len(r.config.ClientCertificates) > 0 {
+		tlsConfig.Certificates = nil
+		tlsConfig.GetClientCertificate = r.getClientCertificate
+	}
+
+	if len(r.config.RootCAs) > 0 && !tlsConfig.InsecureSkipVerify {
+		tlsConfig.RootCAs = r.currentRootCAs()
+		tlsConfig.InsecureSkipVerify = true
+		r.verifiesServer = true
+		r.previousVerify = tlsConfig.VerifyConnection
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			return r.verifyConnection(cs, "")
+		}
+	}
+}
+
+// hostVerifyFunc verifies a TLS connection to host, the host that was dialed.
+type hostVerifyFunc func(cs tls.ConnectionState, host string) error
+
+// installTLSDialer installs a DialTLSContext on transport that verifies each
+// server with verify and the host it dialed. crypto/tls leaves the server name
+// of the connection state empty for IP addresses, so VerifyConnection alone
+// cannot check their IP SANs or pins.
+//
+// net/http does not use DialTLSContext for requests sent through a proxy, so
+// HTTPS requests are taken out of the transport's Proxy and the dialer tunnels
+// to the target through the proxy itself.
+func installTLSDialer(transport *http.Transport, verify hostVerifyFunc) {
+	proxy := transport.Proxy
+	if proxy != nil {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			if req.URL.Scheme == "https" {
+				return nil, nil
+			}
+			return proxy(req)
+		}
+	}
+
+	transport.DialTLSContext = dialTLSContext(transport, verify, proxy)
+}
+
+// dialTLSContext returns the DialTLSContext installed by installTLSDialer.
+// proxy, if set, selects the proxy for addr as the transport's Proxy would
+// for an HTTPS request to it. The handshake is left to the transport.
+func dialTLSContext(transport *http.Transport, verify hostVerifyFunc, proxy func(*http.Request) (*url.URL, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dial := transport.DialContext
+		if dial == nil {
+			dial = defaultDialContext()
+		}
+
+		var proxyURL *url.URL
+		if proxy != nil {
+			req := (&http.Request{
+				Method: http.MethodGet,
+				URL:    &url.URL{Scheme: "https", Host: addr},
+				Header: make(http.Header),
+			}).WithContext(ctx)
+
+			var err error
+			if proxyURL, err = proxy(req); err != nil {
+				return nil, err
+			}
+		}
+
+		var conn net.Conn
+		var err error
+		if proxyURL != nil {
+			conn, err = dialProxy(ctx, dial, transport, proxyURL, addr)
+		} else {
+			conn, err = dial(ctx, network, addr)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+
+		tlsConfig := transport.TLSClientConfig.Clone()
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = host
+		}
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verify(cs, host)
+		}
+
+		return tls.Client(conn, tlsConfig), nil
+	}
+}
+
+func (r *certReloader) load() error {
+	var pool *x509.CertPool
+	if len(r.config.RootCAs) > 0 {
+		var err error
+		pool, err = loadRootCAs(r.basePool, r.config)
+		if err != nil {
+			return err
+		}
+	}
+
+	certs, err := loadClientCertificates(r.config)
+	if err != nil {
+		return err
+	}
+
+	r.rootCAs = pool
+	r.certs = append(append([]tls.Certificate(nil), r.staticCerts...), certs...)
+
+	return nil
+}
+
+func (r *certReloader) statFiles() (map[string]fileStamp, error) {
+	paths := make([]string, 0, len(r.config.RootCAs)+2*len(r.config.ClientCertificates))
+	for _, ca := range r.config.RootCAs {
+		paths = append(paths, ca.File)
+	}
+	for _, cert := range r.config.ClientCertificates {
+		paths = append(paths, cert.CertFile, cert.KeyFile)
+	}
+
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %q: %w", path, err)
+		}
+
+		stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	return stamps, nil
+}
+
+// maybeReload reloads the material if the reload interval has elapsed and any
+// of the files changed since the last successful load.
+func (r *certReloader) maybeReload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.lastCheck) < r.config.ReloadInterval {
+		return
+	}
+	r.lastCheck = now
+
+	stamps, err := r.statFiles()
+	if err != nil {
+		r.logReloadError(err)
+		return
+	}
+
+	if !stampsChanged(r.stamps, stamps) {
+		return
+	}
+
+	if err := r.load(); err != nil {
+		r.logReloadError(err)
+		return
+	}
+
+	r.stamps = stamps
+
+	if !r.logger.IsNoop() {
+		r.logger.Info(context.Background(), "TLS certificates reloaded", map[string]interface{}{
+			"root_cas":            len(r.config.RootCAs),
+			"client_certificates": len(r.config.ClientCertificates),
+		})
+	}
+}
+
+func (r *certReloader) logReloadError(err error) {
+	if r.logger.IsNoop() {
+		return
+	}
+
+	r.logger.Error(context.Background(), "failed to reload TLS certificates, keeping previous ones", map[string]interface{}{
+		"error": err.Error(),
+	})
+}
+
+func stampsChanged(old, current map[string]fileStamp) bool {
+	if len(old) != len(current) {
+		return true
+	}
+
+	for path, stamp := range current {
+		prev, ok := old[path]
+		if !ok || !prev.modTime.Equal(stamp.modTime) || prev.size != stamp.size {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *certReloader) currentRootCAs() *x509.CertPool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rootCAs
+}
+
+func (r *certReloader) getClientCertificate(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+
+	r.mu.Lock()
+	certs := r.certs
+	r.mu.Unlock()
+
+	for i := range certs {
+		if info.SupportsCertificate(&certs[i]) == nil {
+			return &certs[i], nil
+		}
+	}
+
+	if len(certs) > 0 {
+		return &certs[0], nil
+	}
+
+	return &tls.Certificate{}, nil
+}
+
+// verifyConnection verifies the server certificate chain against the current
+// CA pool, then runs the VerifyConnection it replaced and checks the pins
+// against the verified chains. The server name defaults to host, the host
+// that was dialed, if any.
+func (r *certReloader) verifyConnection(cs tls.ConnectionState, host string) error {
+	chains, err := r.verifyChains(cs, host)
+	if err != nil {
+		return err
+	}
+
+	if r.previousVerify != nil {
+		if err := r.previousVerify(cs); err != nil {
+			return err
+		}
+	}
+
+	if r.pins != nil {
+		return r.pins.verifyChains(serverNameOf(cs, host), chains, cs.PeerCertificates)
+	}
+
+	return nil
+}
+
+func (r *certReloader) verifyChains(cs tls.ConnectionState, host string) ([][]*x509.Certificate, error) {
+	r.maybeReload()
+
+	if len(cs.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("tls: server did not present a certificate")
+	}
+
+	serverName := serverNameOf(cs, host)
+	if serverName == "" {
+		return nil, fmt.Errorf("tls: cannot verify server without a server name, set TLSConfig.ServerName")
+	}
+
+	opts := x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         r.currentRootCAs(),
+		Intermediates: x509.NewCertPool(),
+	}
+
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	chains, err := cs.PeerCertificates[0].Verify(opts)
+	if err != nil {
+		return nil, fmt.Errorf("tls: failed to verify server certificate: %w", err)
+	}
+
+	return chains, nil
+}
+
+// serverNameOf returns the server name of cs, or host when it is empty.
+func serverNameOf(cs tls.ConnectionState, host string) string {
+	if cs.ServerName != "" {
+		return cs.ServerName
+	}
+	return host
+}
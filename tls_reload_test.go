@@ -0,0 +1,252 @@
+package vecto
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMutualTLSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// writeRotatedFile replaces the file contents and moves its modification time
+// forward so the change is detected regardless of filesystem time resolution.
+func writeRotatedFile(t *testing.T, path string, data []byte, generation int) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	modTime := time.Now().Add(time.Duration(generation) * time.Minute)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestCertReloader(t *testing.T) {
+	t.Run("reloads rotated client certificates", func(t *testing.T) {
+		srv := newMutualTLSServer(t)
+		dir := t.TempDir()
+
+		caFile := filepath.Join(dir, "ca.pem")
+		certFile := filepath.Join(dir, "client.pem")
+		keyFile := filepath.Join(dir, "client.key")
+
+		require.NoError(t, os.WriteFile(caFile, []byte(serverCertificatePEM(srv)), 0o600))
+		certPEM, keyPEM := generateTestCertificate(t, "client-a")
+		writeRotatedFile(t, certFile, certPEM, 0)
+		writeRotatedFile(t, keyFile, keyPEM, 0)
+
+		v, err := New(Config{
+			BaseURL:       srv.URL,
+			HTTPTransport: &http.Transport{DisableKeepAlives: true},
+			TLS: &TLSConfig{
+				RootCAs:            []CABundle{{File: caFile}},
+				ClientCertificates: []ClientCertificate{{CertFile: certFile, KeyFile: keyFile}},
+				ServerName:         "example.com",
+				ReloadInterval:     time.Millisecond,
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "client-a", res.String())
+
+		certPEM, keyPEM = generateTestCertificate(t, "client-b")
+		writeRotatedFile(t, certFile, certPEM, 1)
+		writeRotatedFile(t, keyFile, keyPEM, 1)
+		time.Sleep(5 * time.Millisecond)
+
+		res, err = v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "client-b", res.String())
+	})
+
+	t.Run("keeps previous certificates when reload fails", func(t *testing.T) {
+		srv := newMutualTLSServer(t)
+		dir := t.TempDir()
+		logger := &mockLogger{}
+
+		certFile := filepath.Join(dir, "client.pem")
+		keyFile := filepath.Join(dir, "client.key")
+		certPEM, keyPEM := generateTestCertificate(t, "client-a")
+		writeRotatedFile(t, certFile, certPEM, 0)
+		writeRotatedFile(t, keyFile, keyPEM, 0)
+
+		v, err := New(Config{
+			BaseURL:       srv.URL,
+			Logger:        logger,
+			HTTPTransport: &http.Transport{DisableKeepAlives: true},
+			TLS: &TLSConfig{
+				RootCAs:            []CABundle{{PEM: serverCertificatePEM(srv)}},
+				ClientCertificates: []ClientCertificate{{CertFile: certFile, KeyFile: keyFile}},
+				ServerName:         "example.com",
+				ReloadInterval:     time.Millisecond,
+			},
+		})
+		require.NoError(t, err)
+
+		writeRotatedFile(t, certFile, []byte("partially written"), 1)
+		time.Sleep(5 * time.Millisecond)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "client-a", res.String())
+
+		found := false
+		for _, call := range logger.errorCalls {
+			if call.msg == "failed to reload TLS certificates, keeping previous ones" {
+				found = true
+			}
+		}
+		assert.True(t, found, "expected reload error to be logged")
+	})
+
+	t.Run("verifies IP addresses against their IP SANs", func(t *testing.T) {
+		srv := newMutualTLSServer(t)
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		writeRotatedFile(t, caFile, []byte(serverCertificatePEM(srv)), 0)
+		certPEM, keyPEM := generateTestCertificate(t, "client-a")
+
+		v, err := New(Config{
+			BaseURL: srv.URL,
+			TLS: &TLSConfig{
+				RootCAs:            []CABundle{{File: caFile}},
+				ClientCertificates: []ClientCertificate{{Cert: string(certPEM), Key: string(keyPEM)}},
+				ReloadInterval:     time.Millisecond,
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "client-a", res.String())
+
+		other, err := New(Config{
+			BaseURL: strings.Replace(srv.URL, "127.0.0.1", "localhost", 1),
+			TLS: &TLSConfig{
+				RootCAs:        []CABundle{{File: caFile}},
+				ReloadInterval: time.Millisecond,
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = other.Get(context.Background(), "/", nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to verify server certificate")
+	})
+
+	t.Run("verifies IP addresses through a proxy", func(t *testing.T) {
+		srv := newMutualTLSServer(t)
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		writeRotatedFile(t, caFile, []byte(serverCertificatePEM(srv)), 0)
+		certPEM, keyPEM := generateTestCertificate(t, "client-a")
+
+		httpProxy := newTestHTTPProxy(t)
+		socksAddr, socksConnections := startTestSOCKS5Proxy(t, "carol", "hunter2")
+
+		for _, proxyURL := range []string{httpProxy.URL, "socks5://carol:hunter2@" + socksAddr} {
+			v, err := New(Config{
+				BaseURL: srv.URL,
+				Proxy:   &ProxyConfig{URL: proxyURL},
+				TLS: &TLSConfig{
+					RootCAs:            []CABundle{{File: caFile}},
+					ClientCertificates: []ClientCertificate{{Cert: string(certPEM), Key: string(keyPEM)}},
+					ReloadInterval:     time.Millisecond,
+				},
+			})
+			require.NoError(t, err)
+
+			res, err := v.Get(context.Background(), "/", nil)
+			require.NoError(t, err, proxyURL)
+			assert.Equal(t, "client-a", res.String())
+		}
+
+		assert.Equal(t, []string{srv.Listener.Addr().String()}, httpProxy.connected)
+		assert.Equal(t, int32(1), atomic.LoadInt32(socksConnections))
+	})
+
+	t.Run("checks pins against the verified chains", func(t *testing.T) {
+		srv := newMutualTLSServer(t)
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		writeRotatedFile(t, caFile, []byte(serverCertificatePEM(srv)), 0)
+		certPEM, keyPEM := generateTestCertificate(t, "client-a")
+
+		newClient := func(pins PinSet) *Vecto {
+			v, err := New(Config{
+				BaseURL:       srv.URL,
+				HTTPTransport: &http.Transport{DisableKeepAlives: true},
+				TLS: &TLSConfig{
+					RootCAs:            []CABundle{{File: caFile}},
+					ClientCertificates: []ClientCertificate{{Cert: string(certPEM), Key: string(keyPEM)}},
+					ServerName:         "example.com",
+					Pins:               map[string]PinSet{"example.com": pins},
+					ReloadInterval:     time.Millisecond,
+				},
+			})
+			require.NoError(t, err)
+			return v
+		}
+
+		_, err := newClient(PinSet{Pins: []string{SPKIPin(srv.Certificate())}}).Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+
+		_, err = newClient(PinSet{Pins: []string{fakePin("other")}}).Get(context.Background(), "/", nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "certificate pin validation failed")
+	})
+
+	t.Run("verifies servers against reloaded CA bundle", func(t *testing.T) {
+		srv := newMutualTLSServer(t)
+		dir := t.TempDir()
+
+		caFile := filepath.Join(dir, "ca.pem")
+		otherCA, _ := generateTestCertificate(t, "other-ca")
+		writeRotatedFile(t, caFile, otherCA, 0)
+
+		certPEM, keyPEM := generateTestCertificate(t, "client-a")
+
+		v, err := New(Config{
+			BaseURL:       srv.URL,
+			HTTPTransport: &http.Transport{DisableKeepAlives: true},
+			TLS: &TLSConfig{
+				RootCAs:            []CABundle{{File: caFile}},
+				ClientCertificates: []ClientCertificate{{Cert: string(certPEM), Key: string(keyPEM)}},
+				ServerName:         "example.com",
+				ReloadInterval:     time.Millisecond,
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = v.Get(context.Background(), "/", nil)
+		require.Error(t, err)
+
+		writeRotatedFile(t, caFile, []byte(serverCertificatePEM(srv)), 1)
+		time.Sleep(5 * time.Millisecond)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "client-a", res.String())
+	})
+}
@@ -0,0 +1,247 @@
+package vecto
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// generateTestCertificate creates a self-signed certificate and returns the
+// PEM-encoded certificate and private key.
+func generateTestCertificate(t *testing.T, commonName string) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{commonName},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM
+}
+
+func serverCertificatePEM(srv *httptest.Server) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+}
+
+func TestValidateTLSConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *TLSConfig
+		wantErr string
+	}{
+		{
+			name:   "nil config",
+			config: nil,
+		},
+		{
+			name:    "empty root CA entry",
+			config:  &TLSConfig{RootCAs: []CABundle{{PEM: "x"}, {}}},
+			wantErr: "root CA 1: PEM or File is required",
+		},
+		{
+			name:    "root CA with PEM and File",
+			config:  &TLSConfig{RootCAs: []CABundle{{PEM: "x", File: "ca.pem"}}},
+			wantErr: "root CA 0: PEM and File are mutually exclusive",
+		},
+		{
+			name:    "client certificate without key",
+			config:  &TLSConfig{ClientCertificates: []ClientCertificate{{Cert: "cert"}}},
+			wantErr: "client certificate 0: both Cert and Key are required",
+		},
+		{
+			name:    "client certificate file without key file",
+			config:  &TLSConfig{ClientCertificates: []ClientCertificate{{CertFile: "a.pem"}}},
+			wantErr: "client certificate 0: both CertFile and KeyFile are required",
+		},
+		{
+			name:    "client certificate mixing inline and files",
+			config:  &TLSConfig{ClientCertificates: []ClientCertificate{{Cert: "c", Key: "k", CertFile: "a.pem", KeyFile: "b.pem"}}},
+			wantErr: "client certificate 0: inline PEM and files are mutually exclusive",
+		},
+		{
+			name:    "unknown TLS version",
+			config:  &TLSConfig{MinVersion: 0x0200},
+			wantErr: "unsupported minimum TLS version",
+		},
+		{
+			name:    "unknown cipher suite",
+			config:  &TLSConfig{CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, 0xffff}},
+			wantErr: "cipher suite 1: unsupported cipher suite 0xffff",
+		},
+		{
+			name: "valid policy",
+			config: &TLSConfig{
+				MinVersion:   tls.VersionTLS12,
+				CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+				NextProtos:   []string{"h2", "http/1.1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTLSConfig(tt.config)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	t.Run("trusts private CA without client certificate", func(t *testing.T) {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		v, err := New(Config{
+			BaseURL: srv.URL,
+			TLS: &TLSConfig{
+				RootCAs: []CABundle{{PEM: serverCertificatePEM(srv)}},
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("offers h2 to HTTP/2 servers", func(t *testing.T) {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}))
+		srv.EnableHTTP2 = true
+		srv.StartTLS()
+		defer srv.Close()
+
+		v, err := New(Config{
+			BaseURL: srv.URL,
+			TLS: &TLSConfig{
+				RootCAs:    []CABundle{{PEM: serverCertificatePEM(srv)}},
+				NextProtos: []string{"h2", "http/1.1"},
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/2.0", res.String())
+	})
+
+	t.Run("loads CA bundles and client certificates from files", func(t *testing.T) {
+		dir := t.TempDir()
+		caPEM, _ := generateTestCertificate(t, "ca.test")
+		certPEM, keyPEM := generateTestCertificate(t, "client.test")
+
+		caFile := filepath.Join(dir, "ca.pem")
+		certFile := filepath.Join(dir, "client.pem")
+		keyFile := filepath.Join(dir, "client.key")
+		require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+		require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+		require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+
+		factory := newHTTPClientFactory(Config{
+			TLS: &TLSConfig{
+				RootCAs:            []CABundle{{File: caFile}},
+				ClientCertificates: []ClientCertificate{{CertFile: certFile, KeyFile: keyFile}},
+				ServerName:         "api.internal",
+				MinVersion:         tls.VersionTLS12,
+				CipherSuites:       []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+				NextProtos:         []string{"http/1.1"},
+			},
+		})
+
+		transport, err := factory.getTransportConfig()
+		require.NoError(t, err)
+
+		tlsConfig := transport.TLSClientConfig
+		require.NotNil(t, tlsConfig)
+		assert.NotNil(t, tlsConfig.RootCAs)
+		assert.Len(t, tlsConfig.Certificates, 1)
+		assert.Equal(t, "api.internal", tlsConfig.ServerName)
+		assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+		assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, tlsConfig.CipherSuites)
+		assert.Equal(t, []string{"http/1.1"}, tlsConfig.NextProtos)
+	})
+
+	t.Run("appends CAs to the system pool", func(t *testing.T) {
+		caPEM, _ := generateTestCertificate(t, "ca.test")
+
+		factory := newHTTPClientFactory(Config{
+			TLS: &TLSConfig{
+				RootCAs:           []CABundle{{PEM: string(caPEM)}},
+				AppendSystemRoots: true,
+			},
+		})
+
+		transport, err := factory.getTransportConfig()
+		require.NoError(t, err)
+		assert.NotNil(t, transport.TLSClientConfig.RootCAs)
+	})
+
+	t.Run("reports which root CA failed", func(t *testing.T) {
+		caPEM, _ := generateTestCertificate(t, "ca.test")
+
+		_, err := New(Config{
+			TLS: &TLSConfig{
+				RootCAs: []CABundle{{PEM: string(caPEM)}, {PEM: "not a certificate"}},
+			},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "root CA 1: no valid PEM certificates found in inline PEM")
+	})
+
+	t.Run("reports which client certificate failed", func(t *testing.T) {
+		missing := filepath.Join(t.TempDir(), "missing.pem")
+
+		_, err := New(Config{
+			TLS: &TLSConfig{
+				ClientCertificates: []ClientCertificate{{CertFile: missing, KeyFile: missing}},
+			},
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "client certificate 0")
+		assert.Contains(t, err.Error(), missing)
+	})
+}
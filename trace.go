@@ -1,10 +1,13 @@
 package vecto
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"net/http"
 	"net/http/httptrace"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,6 +16,10 @@ type TraceInfo struct {
 	// DNSLookup is the time spent performing DNS lookup.
 	DNSLookup time.Duration
 
+	// DNSCacheHit indicates the host was resolved from the DNS cache or a
+	// static host entry instead of a lookup.
+	DNSCacheHit bool
+
 	// TCPConnection is the time spent establishing TCP connection.
 	TCPConnection time.Duration
 
@@ -36,6 +43,78 @@ type TraceInfo struct {
 
 	// ConnIdleTime is how long the connection was idle before this request.
 	ConnIdleTime time.Duration
+
+	// Protocol is the protocol the response was received with, e.g. "HTTP/2.0".
+	Protocol string
+
+	// RemoteAddr is the address of the server the request was sent to.
+	RemoteAddr string
+
+	// RequestWrite is the time spent writing the request, from getting
+	// the connection until the request was fully written.
+	RequestWrite time.Duration
+
+	// TLSVersion is the TLS version of the connection, e.g. tls.VersionTLS13.
+	TLSVersion uint16
+
+	// TLSCipherSuite is the cipher suite of the connection.
+	TLSCipherSuite uint16
+
+	// TLSResumed indicates the TLS session was resumed.
+	TLSResumed bool
+
+	// NegotiatedProtocol is the protocol negotiated with ALPN, e.g. "h2".
+	NegotiatedProtocol string
+
+	// Attempt is the attempt number of this trace, starting at 1.
+	// It is set when the request has a retry configuration.
+	Attempt int
+
+	// Got100Continue indicates the server replied "100 Continue" to an
+	// "Expect: 100-continue" request.
+	Got100Continue bool
+
+	// Connects lists every dial made for the request. Several addresses may
+	// be dialed when a host resolves to more than one IP. TCPConnection spans
+	// from the first dial to the first successful one.
+	Connects []ConnectTrace
+
+	// Events is the timeline of the httptrace events observed until the
+	// response was read. Events that fire later, such as PutIdleConn on some
+	// connections, are not included.
+	Events []TraceEvent
+}
+
+// ConnectTrace describes a single dial made for a request.
+type ConnectTrace struct {
+	Network  string
+	Addr     string
+	Duration time.Duration
+
+	// Error is the dial error, empty when the dial succeeded.
+	Error string
+}
+
+// TraceEvent is an httptrace event observed while sending a request.
+type TraceEvent struct {
+	// Name is the event, e.g. "GotConn", "WroteHeaders" or "PutIdleConn".
+	Name string
+
+	// At is the time of the event since the request started.
+	At time.Duration
+
+	// Detail holds the address or error of the event, if any.
+	Detail string
+}
+
+// eventAt returns the time of the first event with the given name.
+func (t *TraceInfo) eventAt(name string) (time.Duration, bool) {
+	for _, event := range t.Events {
+		if event.Name == name {
+			return event.At, true
+		}
+	}
+	return 0, false
 }
 
 // String returns a formatted string representation of the trace info.
@@ -47,11 +126,32 @@ func (t *TraceInfo) String() string {
 	var b strings.Builder
 	b.WriteString("Request Trace:\n")
 	b.WriteString(fmt.Sprintf("  DNS Lookup:        %v\n", t.DNSLookup))
+	if t.DNSCacheHit {
+		b.WriteString("  DNS Cache Hit:     true\n")
+	}
 	b.WriteString(fmt.Sprintf("  TCP Connection:    %v\n", t.TCPConnection))
 	b.WriteString(fmt.Sprintf("  TLS Handshake:     %v\n", t.TLSHandshake))
 	b.WriteString(fmt.Sprintf("  Server Processing: %v\n", t.ServerProcessing))
 	b.WriteString(fmt.Sprintf("  Content Transfer:  %v\n", t.ContentTransfer))
+	b.WriteString(fmt.Sprintf("  Request Write:     %v\n", t.RequestWrite))
 	b.WriteString(fmt.Sprintf("  Total Time:        %v\n", t.Total))
+	if t.Attempt > 0 {
+		b.WriteString(fmt.Sprintf("  Attempt:           %d\n", t.Attempt))
+	}
+	if t.RemoteAddr != "" {
+		b.WriteString(fmt.Sprintf("  Remote Addr:       %s\n", t.RemoteAddr))
+	}
+	if t.Protocol != "" {
+		b.WriteString(fmt.Sprintf("  Protocol:          %s\n", t.Protocol))
+	}
+	if t.TLSVersion != 0 {
+		b.WriteString(fmt.Sprintf("  TLS Version:       %s\n", tls.VersionName(t.TLSVersion)))
+		b.WriteString(fmt.Sprintf("  TLS Cipher Suite:  %s\n", tls.CipherSuiteName(t.TLSCipherSuite)))
+		b.WriteString(fmt.Sprintf("  TLS Resumed:       %v\n", t.TLSResumed))
+		if t.NegotiatedProtocol != "" {
+			b.WriteString(fmt.Sprintf("  ALPN Protocol:     %s\n", t.NegotiatedProtocol))
+		}
+	}
 	b.WriteString(fmt.Sprintf("  Conn Reused:       %v\n", t.ConnReused))
 	if t.ConnReused {
 		b.WriteString(fmt.Sprintf("  Conn Was Idle:     %v\n", t.ConnWasIdle))
@@ -67,32 +167,33 @@ func (r *Request) ToCurl() string {
 		return ""
 	}
 
+	body := r.encodedBody()
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	rd := r.redaction()
+
 	var b strings.Builder
-	
+
 	b.WriteString("curl -X ")
 	b.WriteString(r.method)
 	b.WriteString(" '")
-	b.WriteString(r.url)
+	b.WriteString(rd.url(r.url))
 	b.WriteString("'")
 
 	for key, value := range r.headers {
 		b.WriteString(" \\\n  -H '")
 		b.WriteString(key)
 		b.WriteString(": ")
-		b.WriteString(value)
+		b.WriteString(rd.header(key, value))
 		b.WriteString("'")
 	}
 
-	if r.data != nil {
-		dataStr := formatDataForCurl(r.data)
-		if dataStr != "" {
-			b.WriteString(" \\\n  -d '")
-			b.WriteString(dataStr)
-			b.WriteString("'")
-		}
+	if len(body) > 0 {
+		b.WriteString(" \\\n  --data-raw '")
+		b.WriteString(strings.ReplaceAll(string(rd.body(body)), "'", `'\''`))
+		b.WriteString("'")
 	}
 
 	return b.String()
@@ -117,7 +218,10 @@ func formatDataForCurl(data interface{}) string {
 }
 
 // traceContext holds timing information during request execution.
+// httptrace hooks may run on transport goroutines, concurrently with each
+// other and with computeTraceInfo, so every field is guarded by mu.
 type traceContext struct {
+	mu               sync.Mutex
 	dnsStart         time.Time
 	dnsDone          time.Time
 	connectStart     time.Time
@@ -130,6 +234,57 @@ type traceContext struct {
 	connReused       bool
 	connWasIdle      bool
 	connIdleTime     time.Duration
+	dnsCacheHit      bool
+	gotConn          time.Time
+	wroteRequest     time.Time
+	remoteAddr       string
+	tlsState         *tls.ConnectionState
+	got100Continue   bool
+	connects         []connectAttempt
+	events           []traceEvent
+}
+
+type connectAttempt struct {
+	network string
+	addr    string
+	start   time.Time
+	done    time.Time
+	err     error
+}
+
+type traceEvent struct {
+	name   string
+	at     time.Time
+	detail string
+}
+
+// record runs update under the lock and appends the event to the timeline.
+func (tc *traceContext) record(name, detail string, update func(now time.Time)) {
+	now := time.Now()
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if update != nil {
+		update(now)
+	}
+	tc.events = append(tc.events, traceEvent{name: name, at: now, detail: detail})
+}
+
+type traceContextKey struct{}
+
+// withTraceContext stores tc in ctx so the dialer can report DNS cache hits.
+func withTraceContext(ctx context.Context, tc *traceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// markDNSCacheHit records a DNS cache hit on the traceContext in ctx, if any.
+func markDNSCacheHit(ctx context.Context) {
+	if tc, ok := ctx.Value(traceContextKey{}).(*traceContext); ok && tc != nil {
+		tc.record("DNSCacheHit", "", func(time.Time) {
+			tc.dnsCacheHit = true
+		})
+	}
 }
 
 // createClientTrace creates an httptrace.ClientTrace for collecting timing information.
@@ -139,47 +294,135 @@ func createClientTrace(tc *traceContext) *httptrace.ClientTrace {
 	}
 
 	return &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			tc.record("GetConn", hostPort, nil)
+		},
 		DNSStart: func(info httptrace.DNSStartInfo) {
-			tc.dnsStart = time.Now()
+			tc.record("DNSStart", info.Host, func(now time.Time) {
+				if tc.dnsStart.IsZero() {
+					tc.dnsStart = now
+				}
+			})
 		},
 		DNSDone: func(info httptrace.DNSDoneInfo) {
-			tc.dnsDone = time.Now()
+			tc.record("DNSDone", errorDetail(info.Err), func(now time.Time) {
+				tc.dnsDone = now
+			})
 		},
 		ConnectStart: func(network, addr string) {
-			tc.connectStart = time.Now()
+			tc.record("ConnectStart", addr, func(now time.Time) {
+				if tc.connectStart.IsZero() {
+					tc.connectStart = now
+				}
+				tc.connects = append(tc.connects, connectAttempt{network: network, addr: addr, start: now})
+			})
 		},
 		ConnectDone: func(network, addr string, err error) {
-			tc.connectDone = time.Now()
+			tc.record("ConnectDone", addrDetail(addr, err), func(now time.Time) {
+				for i := len(tc.connects) - 1; i >= 0; i-- {
+					attempt := &tc.connects[i]
+					if attempt.network == network && attempt.addr == addr && attempt.done.IsZero() {
+						attempt.done = now
+						attempt.err = err
+						break
+					}
+				}
+				if err == nil && tc.connectDone.IsZero() {
+					tc.connectDone = now
+				}
+			})
 		},
 		TLSHandshakeStart: func() {
-			tc.tlsStart = time.Now()
+			tc.record("TLSHandshakeStart", "", func(now time.Time) {
+				tc.tlsStart = now
+			})
 		},
 		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
-			tc.tlsDone = time.Now()
+			tc.record("TLSHandshakeDone", errorDetail(err), func(now time.Time) {
+				tc.tlsDone = now
+				if err == nil {
+					tc.tlsState = &state
+				}
+			})
 		},
-		GotFirstResponseByte: func() {
-			tc.gotFirstResponse = time.Now()
+		GotConn: func(info httptrace.GotConnInfo) {
+			var remoteAddr string
+			if info.Conn != nil {
+				remoteAddr = info.Conn.RemoteAddr().String()
+			}
+			tc.record("GotConn", remoteAddr, func(now time.Time) {
+				tc.gotConn = now
+				tc.remoteAddr = remoteAddr
+				tc.connReused = info.Reused
+				tc.connWasIdle = info.WasIdle
+				tc.connIdleTime = info.IdleTime
+			})
 		},
-		GetConn: func(hostPort string) {
+		WroteHeaders: func() {
+			tc.record("WroteHeaders", "", nil)
 		},
-		GotConn: func(info httptrace.GotConnInfo) {
-			tc.connReused = info.Reused
-			tc.connWasIdle = info.WasIdle
-			tc.connIdleTime = info.IdleTime
+		Wait100Continue: func() {
+			tc.record("Wait100Continue", "", nil)
+		},
+		Got100Continue: func() {
+			tc.record("Got100Continue", "", func(time.Time) {
+				tc.got100Continue = true
+			})
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			tc.record("WroteRequest", errorDetail(info.Err), func(now time.Time) {
+				tc.wroteRequest = now
+			})
+		},
+		GotFirstResponseByte: func() {
+			tc.record("GotFirstResponseByte", "", func(now time.Time) {
+				tc.gotFirstResponse = now
+			})
+		},
+		PutIdleConn: func(err error) {
+			tc.record("PutIdleConn", errorDetail(err), nil)
 		},
 	}
 }
 
+func errorDetail(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func addrDetail(addr string, err error) string {
+	if err == nil {
+		return addr
+	}
+	return addr + ": " + err.Error()
+}
+
 // computeTraceInfo computes TraceInfo from traceContext.
 func computeTraceInfo(tc *traceContext) *TraceInfo {
 	if tc == nil {
 		return nil
 	}
 
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
 	info := &TraceInfo{
-		ConnReused:   tc.connReused,
-		ConnWasIdle:  tc.connWasIdle,
-		ConnIdleTime: tc.connIdleTime,
+		ConnReused:     tc.connReused,
+		ConnWasIdle:    tc.connWasIdle,
+		ConnIdleTime:   tc.connIdleTime,
+		DNSCacheHit:    tc.dnsCacheHit,
+		RemoteAddr:     tc.remoteAddr,
+		Got100Continue: tc.got100Continue,
+	}
+
+	if tc.tlsState != nil {
+		applyTLSState(info, tc.tlsState)
+	}
+
+	if !tc.gotConn.IsZero() && !tc.wroteRequest.IsZero() {
+		info.RequestWrite = tc.wroteRequest.Sub(tc.gotConn)
 	}
 
 	if !tc.dnsStart.IsZero() && !tc.dnsDone.IsZero() {
@@ -206,6 +449,92 @@ func computeTraceInfo(tc *traceContext) *TraceInfo {
 		info.Total = tc.requestEnd.Sub(tc.requestStart)
 	}
 
+	for _, attempt := range tc.connects {
+		connect := ConnectTrace{
+			Network: attempt.network,
+			Addr:    attempt.addr,
+			Error:   errorDetail(attempt.err),
+		}
+		if !attempt.done.IsZero() {
+			connect.Duration = attempt.done.Sub(attempt.start)
+		}
+		info.Connects = append(info.Connects, connect)
+	}
+
+	if len(tc.events) > 0 {
+		info.Events = make([]TraceEvent, 0, len(tc.events))
+		for _, event := range tc.events {
+			traceEvent := TraceEvent{Name: event.name, Detail: event.detail}
+			if !tc.requestStart.IsZero() {
+				traceEvent.At = event.at.Sub(tc.requestStart)
+			}
+			info.Events = append(info.Events, traceEvent)
+		}
+	}
+
 	return info
 }
 
+func applyTLSState(info *TraceInfo, state *tls.ConnectionState) {
+	info.TLSVersion = state.Version
+	info.TLSCipherSuite = state.CipherSuite
+	info.TLSResumed = state.DidResume
+	info.NegotiatedProtocol = state.NegotiatedProtocol
+}
+
+// finishTrace computes the TraceInfo of an attempt once it has ended and
+// records it for the retry loop. The TLS details of the response are used when
+// the connection was reused and no handshake was traced.
+func finishTrace(ctx context.Context, tc *traceContext, httpRes *http.Response) *TraceInfo {
+	if tc == nil {
+		return nil
+	}
+
+	tc.mu.Lock()
+	tc.requestEnd = time.Now()
+	tc.mu.Unlock()
+
+	info := computeTraceInfo(tc)
+
+	if httpRes != nil {
+		info.Protocol = httpRes.Proto
+		if httpRes.TLS != nil {
+			applyTLSState(info, httpRes.TLS)
+		}
+	}
+
+	if traces, ok := ctx.Value(attemptTracesKey{}).(*attemptTraces); ok {
+		traces.add(info)
+	}
+
+	return info
+}
+
+type attemptTracesKey struct{}
+
+// attemptTraces collects the TraceInfo of every attempt of a retried request.
+type attemptTraces struct {
+	mu     sync.Mutex
+	traces []*TraceInfo
+}
+
+func withAttemptTraces(ctx context.Context) (context.Context, *attemptTraces) {
+	traces := &attemptTraces{}
+	return context.WithValue(ctx, attemptTracesKey{}, traces), traces
+}
+
+func (a *attemptTraces) add(info *TraceInfo) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	info.Attempt = len(a.traces) + 1
+	a.traces = append(a.traces, info)
+}
+
+func (a *attemptTraces) list() []*TraceInfo {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.traces) == 0 {
+		return nil
+	}
+	return append([]*TraceInfo(nil), a.traces...)
+}
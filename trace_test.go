@@ -1,8 +1,19 @@
 package vecto
 
 import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTraceInfo_String(t *testing.T) {
@@ -92,7 +103,7 @@ func TestRequest_ToCurl(t *testing.T) {
 			contains: []string{
 				"curl -X POST",
 				"Content-Type: application/json",
-				"Authorization: Bearer token123",
+				"Authorization: [REDACTED]",
 			},
 		},
 		{
@@ -317,13 +328,13 @@ func TestFormatDebugInfo(t *testing.T) {
 		ConnReused: false,
 	}
 
-	result := formatDebugInfo(req, res, trace)
+	result := formatDebugInfo(req, res, trace, nil)
 
 	expectedStrings := []string{
 		"DEBUG INFO",
 		"GET",
 		"https://api.example.com/users",
-		"Authorization: Bearer token",
+		"Authorization: [REDACTED]",
 		"Response Status: 200",
 		"Curl Equivalent",
 	}
@@ -395,3 +406,171 @@ func ExampleRequest_ToCurl() {
 	_ = curlCmd
 }
 
+func TestTraceInfo_ConnectionDetails(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	v, err := New(Config{
+		BaseURL:     srv.URL,
+		EnableTrace: true,
+		TLS: &TLSConfig{
+			RootCAs:    []CABundle{{PEM: serverCertificatePEM(srv)}},
+			ServerName: "example.com",
+		},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+
+		trace := res.TraceInfo
+		require.NotNil(t, trace)
+		assert.Equal(t, srv.Listener.Addr().String(), trace.RemoteAddr)
+		assert.Equal(t, uint16(tls.VersionTLS13), trace.TLSVersion)
+		assert.NotZero(t, trace.TLSCipherSuite)
+		assert.Equal(t, res.RawResponse.TLS.NegotiatedProtocol, trace.NegotiatedProtocol)
+		assert.Equal(t, "HTTP/1.1", trace.Protocol)
+		assert.Positive(t, trace.RequestWrite)
+		assert.Zero(t, trace.Attempt)
+		assert.Equal(t, i > 0, trace.ConnReused)
+		assert.Contains(t, trace.String(), "TLS Version:       TLS 1.3")
+	}
+}
+
+func TestResponse_AttemptTraces(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	v, err := New(Config{
+		BaseURL:     srv.URL,
+		EnableTrace: true,
+		Retry:       &RetryConfig{MaxAttempts: 3, WaitTime: time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	res, err := v.Get(context.Background(), "/", nil)
+	require.NoError(t, err)
+	require.Len(t, res.AttemptTraces, 3)
+
+	for i, trace := range res.AttemptTraces {
+		assert.Equal(t, i+1, trace.Attempt)
+		assert.Equal(t, srv.Listener.Addr().String(), trace.RemoteAddr)
+	}
+	assert.Same(t, res.TraceInfo, res.AttemptTraces[2])
+}
+
+func traceEventNames(trace *TraceInfo) []string {
+	names := make([]string, 0, len(trace.Events))
+	for _, event := range trace.Events {
+		names = append(names, event.Name)
+	}
+	return names
+}
+
+func TestClientTrace_Concurrency(t *testing.T) {
+	t.Run("is race-free with HTTP/2", func(t *testing.T) {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}))
+		srv.EnableHTTP2 = true
+		srv.StartTLS()
+		defer srv.Close()
+
+		v, err := New(Config{
+			BaseURL:     srv.URL,
+			EnableTrace: true,
+			TLS: &TLSConfig{
+				RootCAs:    []CABundle{{PEM: serverCertificatePEM(srv)}},
+				ServerName: "example.com",
+			},
+			Transport: &TransportConfig{HTTP2: &HTTP2Config{}},
+		})
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := v.Post(context.Background(), "/", &RequestOptions{Data: map[string]string{"k": "v"}})
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.Equal(t, "HTTP/2.0", res.String())
+				assert.Contains(t, traceEventNames(res.TraceInfo), "GotConn")
+				assert.Contains(t, traceEventNames(res.TraceInfo), "WroteHeaders")
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("records every connect attempt", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+		defer srv.Close()
+
+		_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+		require.NoError(t, err)
+
+		v, err := New(Config{
+			BaseURL:     "http://api.test:" + port,
+			EnableTrace: true,
+			DNS: &DNSConfig{
+				Hosts: map[string][]string{"api.test": {"127.0.0.2", "127.0.0.1"}},
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+
+		connects := res.TraceInfo.Connects
+		require.Len(t, connects, 2)
+		assert.Equal(t, "127.0.0.2:"+port, connects[0].Addr)
+		assert.Contains(t, connects[0].Error, "connection refused")
+		assert.Equal(t, "127.0.0.1:"+port, connects[1].Addr)
+		assert.Empty(t, connects[1].Error)
+		assert.GreaterOrEqual(t, res.TraceInfo.TCPConnection, connects[1].Duration)
+	})
+
+	t.Run("records 100-continue", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.Write(body)
+		}))
+		defer srv.Close()
+
+		v, err := New(Config{
+			BaseURL:     srv.URL,
+			EnableTrace: true,
+			Transport:   &TransportConfig{ExpectContinueTimeout: time.Second},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Post(context.Background(), "/", &RequestOptions{
+			Data:    map[string]string{"k": "v"},
+			Headers: map[string]string{"Expect": "100-continue"},
+		})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"k":"v"}`, res.String())
+		assert.True(t, res.TraceInfo.Got100Continue)
+
+		names := traceEventNames(res.TraceInfo)
+		assert.Contains(t, names, "Wait100Continue")
+		assert.Contains(t, names, "Got100Continue")
+		assert.Contains(t, names, "WroteRequest")
+	})
+}
@@ -0,0 +1,380 @@
+package vecto
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	traceParentHeader = "traceparent"
+	traceStateHeader  = "tracestate"
+	baggageHeader     = "baggage"
+)
+
+// Tracer starts spans for outgoing requests. It has no dependency on a
+// tracing library, so OpenTelemetry or any other tracer can be adapted to it.
+//
+// Example adapter for OpenTelemetry:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (t otelTracer) StartSpan(ctx context.Context, name string, attrs map[string]any) (context.Context, vecto.Span) {
+//	    ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(toKeyValues(attrs)...))
+//	    return ctx, otelSpan{span}
+//	}
+//
+// where otelSpan implements Span by delegating to trace.Span and converting its
+// trace.SpanContext to a vecto.SpanContext.
+type Tracer interface {
+	// StartSpan starts a client span named name with the given attributes.
+	// The returned context carries the span.
+	StartSpan(ctx context.Context, name string, attrs map[string]any) (context.Context, Span)
+}
+
+// Span is a unit of work started by a Tracer.
+// Attribute keys follow the OpenTelemetry HTTP semantic conventions,
+// e.g. "http.request.method", "url.full" and "http.response.status_code".
+type Span interface {
+	// SpanContext returns the identity of the span, propagated to the server.
+	// A zero SpanContext makes vecto propagate the trace context of the
+	// request context instead.
+	SpanContext() SpanContext
+
+	// SetAttributes adds attributes to the span.
+	SetAttributes(attrs map[string]any)
+
+	// AddEvent records an event, such as a retry, on the span.
+	AddEvent(name string, attrs map[string]any)
+
+	// RecordError marks the span as failed.
+	RecordError(err error)
+
+	// End completes the span.
+	End()
+}
+
+// TracingConfig configures spans and W3C Trace Context propagation.
+type TracingConfig struct {
+	// Tracer starts a span for every request.
+	// Default: nil (no spans; the trace context is still propagated)
+	Tracer Tracer
+
+	// DisablePropagation stops vecto from sending the traceparent,
+	// tracestate and baggage headers.
+	// Default: false
+	DisablePropagation bool
+
+	// Baggage returns the baggage members sent with a request.
+	// Default: BaggageFromContext
+	Baggage func(ctx context.Context) map[string]string
+}
+
+// SpanContext is the W3C Trace Context of a span.
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	TraceFlags byte
+	TraceState string
+}
+
+// IsValid reports whether both the trace ID and the span ID are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// IsSampled reports whether the sampled flag is set.
+func (sc SpanContext) IsSampled() bool {
+	return sc.TraceFlags&0x01 == 0x01
+}
+
+// TraceParent formats the span context as a traceparent header value.
+func (sc SpanContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), sc.TraceFlags)
+}
+
+// ParseTraceParent parses a traceparent header value.
+func ParseTraceParent(value string) (SpanContext, error) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return sc, fmt.Errorf("traceparent must have 4 fields, got: %d", len(parts))
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || version == "ff" {
+		return sc, fmt.Errorf("invalid traceparent version: %q", version)
+	}
+	if version == "00" && len(parts) != 4 {
+		return sc, fmt.Errorf("traceparent version 00 must have 4 fields, got: %d", len(parts))
+	}
+
+	if len(traceID) != 32 || strings.ToLower(traceID) != traceID {
+		return sc, fmt.Errorf("invalid trace ID: %q", traceID)
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(traceID)); err != nil {
+		return sc, fmt.Errorf("invalid trace ID: %w", err)
+	}
+
+	if len(spanID) != 16 || strings.ToLower(spanID) != spanID {
+		return sc, fmt.Errorf("invalid span ID: %q", spanID)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(spanID)); err != nil {
+		return sc, fmt.Errorf("invalid span ID: %w", err)
+	}
+
+	flagBytes, err := hex.DecodeString(flags)
+	if err != nil || len(flagBytes) != 1 {
+		return sc, fmt.Errorf("invalid trace flags: %q", flags)
+	}
+	sc.TraceFlags = flagBytes[0]
+
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("trace ID and span ID cannot be all zeros")
+	}
+
+	return sc, nil
+}
+
+type spanContextKey struct{}
+
+type baggageKey struct{}
+
+type spanKey struct{}
+
+// ContextWithSpanContext returns a context carrying sc, propagated to the
+// server when no Tracer span overrides it.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the SpanContext set with ContextWithSpanContext.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// ContextWithBaggage returns a context carrying the baggage members.
+func ContextWithBaggage(ctx context.Context, baggage map[string]string) context.Context {
+	members := make(map[string]string, len(baggage))
+	for k, v := range baggage {
+		members[k] = v
+	}
+	return context.WithValue(ctx, baggageKey{}, members)
+}
+
+// BaggageFromContext returns the baggage members set with ContextWithBaggage.
+func BaggageFromContext(ctx context.Context) map[string]string {
+	baggage, _ := ctx.Value(baggageKey{}).(map[string]string)
+	return baggage
+}
+
+// formatBaggage encodes baggage members as a baggage header value, sorted by key.
+func formatBaggage(baggage map[string]string) string {
+	keys := make([]string, 0, len(baggage))
+	for k := range baggage {
+		if isBaggageKey(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	members := make([]string, 0, len(keys))
+	for _, k := range keys {
+		members = append(members, k+"="+strings.ReplaceAll(url.QueryEscape(baggage[k]), "+", "%20"))
+	}
+
+	return strings.Join(members, ",")
+}
+
+// isBaggageKey reports whether key is an RFC 7230 token, as baggage keys must be.
+func isBaggageKey(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// startSpan starts the span of a logical request, covering all of its attempts.
+// The span is named "{method} {route}", or just the method if there is no route.
+func (v *Vecto) startSpan(ctx context.Context, method, route string) (context.Context, Span) {
+	if v.config.Tracing == nil || v.config.Tracing.Tracer == nil {
+		return ctx, nil
+	}
+
+	name := method
+	attrs := map[string]any{"http.request.method": method}
+	if route != "" {
+		name = method + " " + route
+		attrs["http.route"] = route
+	}
+
+	ctx, span := v.config.Tracing.Tracer.StartSpan(ctx, name, attrs)
+	if span == nil {
+		return ctx, nil
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func spanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanKey{}).(Span)
+	return span
+}
+
+// addSpanEvent records an event on the request span in ctx, if any.
+func addSpanEvent(ctx context.Context, name string, attrs map[string]any) {
+	if span := spanFromContext(ctx); span != nil {
+		span.AddEvent(name, attrs)
+	}
+}
+
+// injectTraceContext sets the redacted URL attributes on the span and adds the
+// traceparent, tracestate and baggage headers unless the request already has them.
+func (v *Vecto) injectTraceContext(ctx context.Context, req *Request) {
+	if v.config.Tracing == nil {
+		return
+	}
+
+	span := spanFromContext(ctx)
+	if span != nil {
+		span.SetAttributes(urlAttributes(req.redaction().url(req.FullUrl())))
+	}
+
+	if v.config.Tracing.DisablePropagation {
+		return
+	}
+
+	existing := make(map[string]bool, 3)
+	for key := range req.Headers() {
+		existing[strings.ToLower(key)] = true
+	}
+
+	sc, ok := SpanContextFromContext(ctx)
+	if span != nil && span.SpanContext().IsValid() {
+		sc, ok = span.SpanContext(), true
+	}
+
+	if ok && !existing[traceParentHeader] {
+		req.SetHeader(traceParentHeader, sc.TraceParent())
+		if sc.TraceState != "" && !existing[traceStateHeader] {
+			req.SetHeader(traceStateHeader, sc.TraceState)
+		}
+	}
+
+	baggageFunc := v.config.Tracing.Baggage
+	if baggageFunc == nil {
+		baggageFunc = BaggageFromContext
+	}
+
+	if baggage := formatBaggage(baggageFunc(ctx)); baggage != "" && !existing[baggageHeader] {
+		req.SetHeader(baggageHeader, baggage)
+	}
+}
+
+func urlAttributes(rawURL string) map[string]any {
+	attrs := map[string]any{"url.full": rawURL}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return attrs
+	}
+
+	attrs["url.scheme"] = u.Scheme
+	attrs["server.address"] = u.Hostname()
+
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "http":
+			port = "80"
+		}
+	}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs["server.port"] = p
+	}
+
+	return attrs
+}
+
+// endSpan records the outcome of the request and ends the span.
+func endSpan(span Span, res *Response, err error) {
+	if span == nil {
+		return
+	}
+
+	if res != nil {
+		attrs := map[string]any{"http.response.status_code": res.StatusCode}
+		if version := protocolVersion(res.Protocol); version != "" {
+			attrs["network.protocol.version"] = version
+		}
+		if res.StatusCode >= 400 {
+			attrs["error.type"] = strconv.Itoa(res.StatusCode)
+		}
+		if res.IsFallback() {
+			attrs["vecto.fallback"] = true
+		}
+		span.SetAttributes(attrs)
+	}
+
+	if err != nil {
+		span.SetAttributes(map[string]any{"error.type": errorType(err)})
+		span.RecordError(err)
+	}
+
+	span.End()
+}
+
+// protocolVersion converts "HTTP/1.1" to "1.1" and "HTTP/2.0" to "2".
+// HTTP/1.0 keeps its minor version.
+func protocolVersion(proto string) string {
+	version := strings.TrimPrefix(proto, "HTTP/")
+	if version == proto {
+		return ""
+	}
+
+	switch version {
+	case "2.0":
+		return "2"
+	case "3.0":
+		return "3"
+	}
+	return version
+}
+
+func errorType(err error) string {
+	var cbErr *CircuitBreakerError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &cbErr):
+		return "circuit_breaker_open"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+
+	for errors.Unwrap(err) != nil {
+		err = errors.Unwrap(err)
+	}
+	return fmt.Sprintf("%T", err)
+}
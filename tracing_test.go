@@ -0,0 +1,342 @@
+package vecto
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type spanEvent struct {
+	name  string
+	attrs map[string]any
+}
+
+type mockSpan struct {
+	mu     sync.Mutex
+	name   string
+	sc     SpanContext
+	attrs  map[string]any
+	events []spanEvent
+	errs   []error
+	ended  int
+}
+
+func (s *mockSpan) SpanContext() SpanContext {
+	return s.sc
+}
+
+func (s *mockSpan) SetAttributes(attrs map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range attrs {
+		s.attrs[k] = v
+	}
+}
+
+func (s *mockSpan) AddEvent(name string, attrs map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, spanEvent{name: name, attrs: attrs})
+}
+
+func (s *mockSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+func (s *mockSpan) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ended++
+}
+
+type mockTracer struct {
+	mu    sync.Mutex
+	sc    SpanContext
+	spans []*mockSpan
+}
+
+func (t *mockTracer) StartSpan(ctx context.Context, name string, attrs map[string]any) (context.Context, Span) {
+	span := &mockSpan{name: name, sc: t.sc, attrs: map[string]any{}}
+	span.SetAttributes(attrs)
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+
+	return ctx, span
+}
+
+func (t *mockTracer) lastSpan() *mockSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.spans) == 0 {
+		return nil
+	}
+	return t.spans[len(t.spans)-1]
+}
+
+func newHeaderEchoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range []string{"traceparent", "tracestate", "baggage"} {
+			w.Header().Set("Echo-"+name, r.Header.Get(name))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func mustParseTraceParent(t *testing.T, value string) SpanContext {
+	t.Helper()
+
+	sc, err := ParseTraceParent(value)
+	require.NoError(t, err)
+	return sc
+}
+
+func TestParseTraceParent(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, err := ParseTraceParent(value)
+	require.NoError(t, err)
+	assert.True(t, sc.IsValid())
+	assert.True(t, sc.IsSampled())
+	assert.Equal(t, value, sc.TraceParent())
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+	}
+	for _, value := range invalid {
+		_, err := ParseTraceParent(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	tests := []struct {
+		proto string
+		want  string
+	}{
+		{"HTTP/1.0", "1.0"},
+		{"HTTP/1.1", "1.1"},
+		{"HTTP/2.0", "2"},
+		{"HTTP/3.0", "3"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, protocolVersion(tt.proto), tt.proto)
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	t.Run("propagates the context trace without a tracer", func(t *testing.T) {
+		srv := newHeaderEchoServer(t)
+
+		v, err := New(Config{BaseURL: srv.URL, Tracing: &TracingConfig{}})
+		require.NoError(t, err)
+
+		sc := mustParseTraceParent(t, parent)
+		sc.TraceState = "vendor=value"
+		ctx := ContextWithSpanContext(context.Background(), sc)
+		ctx = ContextWithBaggage(ctx, map[string]string{"user.id": "42", "tenant": "acme corp"})
+
+		res, err := v.Get(ctx, "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, parent, res.Header("Echo-traceparent"))
+		assert.Equal(t, "vendor=value", res.Header("Echo-tracestate"))
+		assert.Equal(t, "tenant=acme%20corp,user.id=42", res.Header("Echo-baggage"))
+	})
+
+	t.Run("prefers the span context of the tracer", func(t *testing.T) {
+		srv := newHeaderEchoServer(t)
+		child := "00-4bf92f3577b34da6a3ce929d0e0e4736-b7ad6b7169203331-01"
+		tracer := &mockTracer{sc: mustParseTraceParent(t, child)}
+
+		v, err := New(Config{BaseURL: srv.URL, Tracing: &TracingConfig{Tracer: tracer}})
+		require.NoError(t, err)
+
+		ctx := ContextWithSpanContext(context.Background(), mustParseTraceParent(t, parent))
+		res, err := v.Get(ctx, "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, child, res.Header("Echo-traceparent"))
+	})
+
+	t.Run("keeps headers set on the request", func(t *testing.T) {
+		srv := newHeaderEchoServer(t)
+		custom := "00-11111111111111111111111111111111-2222222222222222-00"
+
+		v, err := New(Config{BaseURL: srv.URL, Tracing: &TracingConfig{}})
+		require.NoError(t, err)
+
+		ctx := ContextWithSpanContext(context.Background(), mustParseTraceParent(t, parent))
+		res, err := v.Get(ctx, "/", &RequestOptions{Headers: map[string]string{"Traceparent": custom}})
+		require.NoError(t, err)
+		assert.Equal(t, custom, res.Header("Echo-traceparent"))
+	})
+
+	t.Run("can be disabled", func(t *testing.T) {
+		srv := newHeaderEchoServer(t)
+
+		v, err := New(Config{BaseURL: srv.URL, Tracing: &TracingConfig{DisablePropagation: true}})
+		require.NoError(t, err)
+
+		ctx := ContextWithSpanContext(context.Background(), mustParseTraceParent(t, parent))
+		ctx = ContextWithBaggage(ctx, map[string]string{"k": "v"})
+		res, err := v.Get(ctx, "/", nil)
+		require.NoError(t, err)
+		assert.Empty(t, res.Header("Echo-traceparent"))
+		assert.Empty(t, res.Header("Echo-baggage"))
+	})
+
+	t.Run("uses a custom baggage source", func(t *testing.T) {
+		srv := newHeaderEchoServer(t)
+
+		v, err := New(Config{
+			BaseURL: srv.URL,
+			Tracing: &TracingConfig{
+				Baggage: func(ctx context.Context) map[string]string {
+					return map[string]string{"region": "eu"}
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		res, err := v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "region=eu", res.Header("Echo-baggage"))
+		assert.Empty(t, res.Header("Echo-traceparent"))
+	})
+}
+
+func TestTracerSpans(t *testing.T) {
+	t.Run("records HTTP attributes", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer srv.Close()
+
+		tracer := &mockTracer{}
+		v, err := New(Config{BaseURL: srv.URL, Tracing: &TracingConfig{Tracer: tracer}})
+		require.NoError(t, err)
+
+		_, err = v.Get(context.Background(), "/pets/1", nil)
+		require.NoError(t, err)
+
+		span := tracer.lastSpan()
+		require.NotNil(t, span)
+		assert.Equal(t, "GET", span.name)
+		assert.Equal(t, 1, span.ended)
+		assert.Equal(t, "GET", span.attrs["http.request.method"])
+		assert.Equal(t, srv.URL+"/pets/1", span.attrs["url.full"])
+		assert.Equal(t, "127.0.0.1", span.attrs["server.address"])
+		assert.Equal(t, http.StatusNotFound, span.attrs["http.response.status_code"])
+		assert.Equal(t, "404", span.attrs["error.type"])
+		assert.Equal(t, "1.1", span.attrs["network.protocol.version"])
+		assert.Empty(t, span.errs)
+	})
+
+	t.Run("redacts the URL", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+
+		tracer := &mockTracer{}
+		v, err := New(Config{
+			BaseURL:   srv.URL,
+			Tracing:   &TracingConfig{Tracer: tracer},
+			Redaction: &RedactionConfig{QueryParams: []string{"token"}},
+		})
+		require.NoError(t, err)
+
+		_, err = v.Get(context.Background(), "/pets?token=secret&page=2", nil)
+		require.NoError(t, err)
+
+		span := tracer.lastSpan()
+		require.NotNil(t, span)
+		fullURL, ok := span.attrs["url.full"].(string)
+		require.True(t, ok)
+		assert.Contains(t, fullURL, "token=[REDACTED]")
+		assert.NotContains(t, fullURL, "secret")
+		assert.Equal(t, "127.0.0.1", span.attrs["server.address"])
+	})
+
+	t.Run("records retries as events", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer srv.Close()
+
+		tracer := &mockTracer{}
+		v, err := New(Config{
+			BaseURL: srv.URL,
+			Retry:   &RetryConfig{MaxAttempts: 3, WaitTime: time.Millisecond},
+			Tracing: &TracingConfig{Tracer: tracer},
+		})
+		require.NoError(t, err)
+
+		_, err = v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+
+		span := tracer.lastSpan()
+		require.Len(t, span.events, 2)
+		assert.Equal(t, "retry", span.events[0].name)
+		assert.Equal(t, 1, span.events[0].attrs["http.request.resend_count"])
+		assert.Equal(t, http.StatusServiceUnavailable, span.events[0].attrs["http.response.status_code"])
+		assert.Equal(t, 2, span.events[1].attrs["http.request.resend_count"])
+		assert.Equal(t, http.StatusOK, span.attrs["http.response.status_code"])
+		assert.Empty(t, span.errs)
+	})
+
+	t.Run("records circuit breaker rejections", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		cbConfig := DefaultCircuitBreakerConfig()
+		cbConfig.FailureThreshold = 1
+
+		tracer := &mockTracer{}
+		v, err := New(Config{
+			BaseURL:        srv.URL,
+			CircuitBreaker: &cbConfig,
+			Tracing:        &TracingConfig{Tracer: tracer},
+		})
+		require.NoError(t, err)
+
+		_, _ = v.Get(context.Background(), "/", nil)
+		_, err = v.Get(context.Background(), "/", nil)
+
+		var cbErr *CircuitBreakerError
+		require.True(t, errors.As(err, &cbErr))
+
+		span := tracer.lastSpan()
+		require.Len(t, span.events, 1)
+		assert.Equal(t, "circuit_breaker.rejected", span.events[0].name)
+		assert.Equal(t, "open", span.events[0].attrs["circuit_breaker.state"])
+		assert.Equal(t, "circuit_breaker_open", span.attrs["error.type"])
+		assert.Equal(t, 1, span.ended)
+	})
+}
@@ -0,0 +1,254 @@
+package vecto
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TransportConfig tunes the connection pool and timeouts of the transport.
+// It is applied on top of the certificate, TLS, proxy and dial settings.
+// Zero values keep the defaults of net/http.
+type TransportConfig struct {
+	// MaxIdleConns limits the idle connections kept across all hosts.
+	// Default: 0 (no limit)
+	MaxIdleConns int
+
+	// MaxIdleConnsPerHost limits the idle connections kept per host.
+	// Default: 0 (net/http keeps 2)
+	MaxIdleConnsPerHost int
+
+	// MaxConnsPerHost limits the connections per host, including those in use.
+	// Requests wait for a free connection once the limit is reached.
+	// Default: 0 (no limit)
+	MaxConnsPerHost int
+
+	// IdleConnTimeout is how long an idle connection is kept in the pool.
+	// Default: 50s
+	IdleConnTimeout time.Duration
+
+	// ResponseHeaderTimeout limits the wait for the response headers once
+	// the request has been written.
+	// Default: 0 (no timeout)
+	ResponseHeaderTimeout time.Duration
+
+	// ExpectContinueTimeout limits the wait for a "100 Continue" response
+	// when the request has an "Expect: 100-continue" header.
+	// Default: 0 (the body is sent immediately)
+	ExpectContinueTimeout time.Duration
+
+	// TLSHandshakeTimeout limits the TLS handshake. It does not apply when
+	// TLSConfig.Pins or reloaded TLSConfig.RootCAs are set: vecto then dials
+	// TLS itself, net/http does not time that handshake, and it is bounded
+	// by Config.Timeout and the request context only.
+	// Default: 0 (no timeout)
+	TLSHandshakeTimeout time.Duration
+
+	// DisableKeepAlives uses each connection for a single request.
+	// Default: false
+	DisableKeepAlives bool
+
+	// ForceAttemptHTTP2 tries HTTP/2 even when a custom TLS config or dialer is set.
+	// Default: false
+	ForceAttemptHTTP2 bool
+
+	// HTTP2 tunes HTTP/2 and enables h2c.
+	// Default: nil
+	HTTP2 *HTTP2Config
+}
+
+func validateTransportConfig(config *TransportConfig) error {
+	if config == nil {
+		return nil
+	}
+
+	counts := map[string]int{
+		"max idle connections":          config.MaxIdleConns,
+		"max idle connections per host": config.MaxIdleConnsPerHost,
+		"max connections per host":      config.MaxConnsPerHost,
+	}
+	for name, value := range counts {
+		if value < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+
+	timeouts := map[string]time.Duration{
+		"idle connection timeout": config.IdleConnTimeout,
+		"response header timeout": config.ResponseHeaderTimeout,
+		"expect continue timeout": config.ExpectContinueTimeout,
+		"TLS handshake timeout":   config.TLSHandshakeTimeout,
+	}
+	for name, value := range timeouts {
+		if value < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+
+	return validateHTTP2Config(config.HTTP2)
+}
+
+// applyTransportConfig copies the non-zero settings onto transport.
+func applyTransportConfig(transport *http.Transport, config *TransportConfig) {
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = config.MaxConnsPerHost
+	}
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+	if config.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+	}
+	if config.ExpectContinueTimeout > 0 {
+		transport.ExpectContinueTimeout = config.ExpectContinueTimeout
+	}
+	if config.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+	}
+	if config.DisableKeepAlives {
+		transport.DisableKeepAlives = true
+	}
+	if config.ForceAttemptHTTP2 {
+		transport.ForceAttemptHTTP2 = true
+	}
+	if config.HTTP2 != nil {
+		applyHTTP2Config(transport, config.HTTP2)
+	}
+}
+
+// PoolStats is a snapshot of the connection pool.
+type PoolStats struct {
+	// OpenConnections is the number of connections currently open.
+	OpenConnections int64
+
+	// ActiveConnections is the number of connections currently serving a request.
+	// With HTTP/2 each in-flight request is counted.
+	ActiveConnections int64
+
+	// IdleConnections is the number of open connections not serving a request.
+	IdleConnections int64
+
+	// Dials is the total number of connections dialed successfully.
+	Dials int64
+
+	// DialErrors is the total number of failed dials.
+	DialErrors int64
+
+	// Reused is the total number of requests sent on a reused connection.
+	Reused int64
+
+	// Closed is the total number of connections closed.
+	Closed int64
+}
+
+// poolTracker counts connections dialed by the transport and requests using them.
+type poolTracker struct {
+	open       atomic.Int64
+	active     atomic.Int64
+	dials      atomic.Int64
+	dialErrors atomic.Int64
+	reused     atomic.Int64
+	closed     atomic.Int64
+}
+
+func newPoolTracker() *poolTracker {
+	return &poolTracker{}
+}
+
+func (p *poolTracker) stats() PoolStats {
+	stats := PoolStats{
+		OpenConnections:   p.open.Load(),
+		ActiveConnections: p.active.Load(),
+		Dials:             p.dials.Load(),
+		DialErrors:        p.dialErrors.Load(),
+		Reused:            p.reused.Load(),
+		Closed:            p.closed.Load(),
+	}
+
+	stats.IdleConnections = stats.OpenConnections - stats.ActiveConnections
+	if stats.IdleConnections < 0 {
+		stats.IdleConnections = 0
+	}
+
+	return stats
+}
+
+// trackDial counts the connections made by dial and their closing.
+func (p *poolTracker) trackDial(dial DialContextFunc) DialContextFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			p.dialErrors.Add(1)
+			return nil, err
+		}
+
+		p.dials.Add(1)
+		p.open.Add(1)
+
+		return &trackedConn{Conn: conn, pool: p}, nil
+	}
+}
+
+// trackRequest counts the request in ctx as active from GotConn until the
+// returned release func is called.
+func (p *poolTracker) trackRequest(ctx context.Context) (context.Context, func()) {
+	var gotConn atomic.Bool
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if gotConn.Swap(true) {
+				return
+			}
+			p.active.Add(1)
+			if info.Reused {
+				p.reused.Add(1)
+			}
+		},
+	}
+
+	release := func() {
+		if gotConn.Swap(false) {
+			p.active.Add(-1)
+		}
+	}
+
+	return httptrace.WithClientTrace(ctx, trace), release
+}
+
+type trackedConn struct {
+	net.Conn
+	pool *poolTracker
+	once sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() {
+		c.pool.open.Add(-1)
+		c.pool.closed.Add(1)
+	})
+	return c.Conn.Close()
+}
+
+// PoolStats returns a snapshot of the connection pool.
+// Connections are tracked on the transports configured by vecto, including
+// the clone of a supplied *http.Transport; when HTTPTransport is another
+// round tripper, the returned stats are zero.
+func (v *Vecto) PoolStats() PoolStats {
+	client, ok := v.client.(*DefaultClient)
+	if !ok || client.pool == nil {
+		return PoolStats{}
+	}
+
+	return client.pool.stats()
+}
@@ -0,0 +1,247 @@
+package vecto
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTransportConfig(t *testing.T) {
+	assert.NoError(t, validateTransportConfig(nil))
+	assert.NoError(t, validateTransportConfig(&TransportConfig{MaxIdleConnsPerHost: 32}))
+
+	err := validateTransportConfig(&TransportConfig{MaxConnsPerHost: -1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max connections per host cannot be negative")
+
+	_, err = New(Config{Transport: &TransportConfig{TLSHandshakeTimeout: -time.Second}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid transport config: TLS handshake timeout cannot be negative")
+}
+
+func TestTransportConfig(t *testing.T) {
+	pool := &TransportConfig{
+		MaxIdleConns:          64,
+		MaxIdleConnsPerHost:   16,
+		MaxConnsPerHost:       32,
+		IdleConnTimeout:       90 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		TLSHandshakeTimeout:   3 * time.Second,
+		DisableKeepAlives:     true,
+		ForceAttemptHTTP2:     true,
+	}
+
+	assertApplied := func(t *testing.T, transport *http.Transport) {
+		assert.Equal(t, 64, transport.MaxIdleConns)
+		assert.Equal(t, 16, transport.MaxIdleConnsPerHost)
+		assert.Equal(t, 32, transport.MaxConnsPerHost)
+		assert.Equal(t, 90*time.Second, transport.IdleConnTimeout)
+		assert.Equal(t, 5*time.Second, transport.ResponseHeaderTimeout)
+		assert.Equal(t, time.Second, transport.ExpectContinueTimeout)
+		assert.Equal(t, 3*time.Second, transport.TLSHandshakeTimeout)
+		assert.True(t, transport.DisableKeepAlives)
+		assert.True(t, transport.ForceAttemptHTTP2)
+	}
+
+	t.Run("merges with TLS settings", func(t *testing.T) {
+		factory := newHTTPClientFactory(Config{
+			InsecureSkipVerify: true,
+			Transport:          pool,
+		})
+
+		rt, err := factory.getTransport()
+		require.NoError(t, err)
+
+		transport := rt.(*http.Transport)
+		assertApplied(t, transport)
+		require.NotNil(t, transport.TLSClientConfig)
+		assert.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+	})
+
+	t.Run("applies to a clone of a custom transport", func(t *testing.T) {
+		customTransport := &http.Transport{MaxIdleConns: 1}
+		factory := newHTTPClientFactory(Config{
+			HTTPTransport: customTransport,
+			Transport:     pool,
+		})
+
+		rt, err := factory.getTransport()
+		require.NoError(t, err)
+
+		assert.NotSame(t, customTransport, rt)
+		assertApplied(t, rt.(*http.Transport))
+		assert.Equal(t, 1, customTransport.MaxIdleConns)
+	})
+
+	t.Run("keeps defaults for zero values", func(t *testing.T) {
+		factory := newHTTPClientFactory(Config{Transport: &TransportConfig{MaxIdleConnsPerHost: 8}})
+
+		rt, err := factory.getTransport()
+		require.NoError(t, err)
+
+		transport := rt.(*http.Transport)
+		assert.Equal(t, 8, transport.MaxIdleConnsPerHost)
+		assert.Equal(t, defaultIdleConnTimeout, transport.IdleConnTimeout)
+		assert.False(t, transport.DisableKeepAlives)
+	})
+}
+
+func TestTLSHandshakeTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// Accept connections and never answer the client hello.
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	get := func(t *testing.T, tlsConfig *TLSConfig) error {
+		v, err := New(Config{
+			BaseURL:   "https://" + listener.Addr().String(),
+			Timeout:   500 * time.Millisecond,
+			Transport: &TransportConfig{TLSHandshakeTimeout: 50 * time.Millisecond},
+			TLS:       tlsConfig,
+		})
+		require.NoError(t, err)
+
+		_, err = v.Get(context.Background(), "/", nil)
+		return err
+	}
+
+	t.Run("limits the handshake", func(t *testing.T) {
+		err := get(t, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "TLS handshake timeout")
+	})
+
+	t.Run("is not applied with pins", func(t *testing.T) {
+		err := get(t, &TLSConfig{Pins: map[string]PinSet{"127.0.0.1": {Pins: []string{fakePin("pin")}}}})
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "TLS handshake timeout")
+		assert.Contains(t, err.Error(), "Client.Timeout exceeded", "the request timeout still bounds the handshake")
+	})
+}
+
+func TestPoolStats(t *testing.T) {
+	t.Run("tracks reused connections", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+		defer srv.Close()
+
+		v, err := New(Config{BaseURL: srv.URL})
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			_, err := v.Get(context.Background(), "/", nil)
+			require.NoError(t, err)
+		}
+
+		stats := v.PoolStats()
+		assert.Equal(t, int64(1), stats.Dials)
+		assert.Equal(t, int64(2), stats.Reused)
+		assert.Equal(t, int64(1), stats.OpenConnections)
+		assert.Equal(t, int64(0), stats.ActiveConnections)
+		assert.Equal(t, int64(1), stats.IdleConnections)
+	})
+
+	t.Run("tracks connections of a supplied transport", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+		defer srv.Close()
+
+		v, err := New(Config{BaseURL: srv.URL, HTTPTransport: &http.Transport{MaxIdleConns: 4}})
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err := v.Get(context.Background(), "/", nil)
+			require.NoError(t, err)
+		}
+
+		stats := v.PoolStats()
+		assert.Equal(t, int64(1), stats.Dials)
+		assert.Equal(t, int64(1), stats.Reused)
+	})
+
+	t.Run("tracks active and closed connections", func(t *testing.T) {
+		release := make(chan struct{})
+		started := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.Write([]byte("ok"))
+		}))
+		defer srv.Close()
+
+		v, err := New(Config{
+			BaseURL:   srv.URL,
+			Transport: &TransportConfig{DisableKeepAlives: true},
+		})
+		require.NoError(t, err)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := v.Get(context.Background(), "/", nil)
+			done <- err
+		}()
+
+		<-started
+		stats := v.PoolStats()
+		assert.Equal(t, int64(1), stats.ActiveConnections)
+		assert.Equal(t, int64(1), stats.OpenConnections)
+		assert.Equal(t, int64(0), stats.IdleConnections)
+
+		close(release)
+		require.NoError(t, <-done)
+
+		assert.Eventually(t, func() bool {
+			stats := v.PoolStats()
+			return stats.OpenConnections == 0 && stats.Closed == 1 && stats.ActiveConnections == 0
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("counts dial errors", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		url := srv.URL
+		srv.Close()
+
+		v, err := New(Config{BaseURL: url})
+		require.NoError(t, err)
+
+		_, err = v.Get(context.Background(), "/", nil)
+		require.Error(t, err)
+
+		stats := v.PoolStats()
+		assert.Equal(t, int64(1), stats.DialErrors)
+		assert.Equal(t, int64(0), stats.ActiveConnections)
+	})
+
+	t.Run("is zero for custom round trippers", func(t *testing.T) {
+		v, err := New(Config{
+			BaseURL: "http://example.com",
+			HTTPTransport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+			}),
+		})
+		require.NoError(t, err)
+
+		_, err = v.Get(context.Background(), "/", nil)
+		require.NoError(t, err)
+		assert.Equal(t, PoolStats{}, v.PoolStats())
+	})
+}
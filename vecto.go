@@ -2,10 +2,13 @@ package vecto
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"maps"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,6 +26,8 @@ type Vecto struct {
 	circuitBreakerMgr *CircuitBreakerManager
 	channelDispatcher *channelDispatcher
 	requestHandler    *requestHandler
+	redactor          *redactor
+	debugMu           sync.Mutex
 }
 
 var defaultConfig = Config{
@@ -48,13 +53,14 @@ func New(config Config) (v *Vecto, err error) {
 	mergedConfig := mergeConfig(config, defaultConfig)
 
 	instance := Vecto{
-		config: mergedConfig,
+		config:   mergedConfig,
+		redactor: newRedactor(mergedConfig.Redaction),
 	}
 
 	if mergedConfig.Logger == nil {
 		instance.logger = newNoopLogger()
 	} else {
-		instance.logger = mergedConfig.Logger
+		instance.logger = newLevelLogger(mergedConfig.Logger, mergedConfig.LogLevel)
 	}
 
 	if mergedConfig.CircuitBreaker != nil {
@@ -62,7 +68,9 @@ func New(config Config) (v *Vecto, err error) {
 		if cbConfig.Logger == nil {
 			cbConfig.Logger = instance.logger
 		}
+		cbConfig.OnStateChange = circuitBreakerStateHook(mergedConfig.MetricsCollector, cbConfig.OnStateChange)
 		instance.circuitBreakerMgr = NewCircuitBreakerManager(cbConfig, instance.logger)
+		instance.circuitBreakerMgr.onCreate = circuitBreakerCreateHook(mergedConfig.MetricsCollector)
 	}
 
 	err = instance.setHTTPClient()
@@ -106,149 +114,245 @@ func (v *Vecto) Options(ctx context.Context, url string, options *RequestOptions
 }
 
 func (v *Vecto) Request(ctx context.Context, url string, method string, options *RequestOptions) (res *Response, err error) {
-	startTime := time.Now()
-
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	ctx = ensureRequestID(ctx)
+
+	startTime := time.Now()
 
 	request, err := v.newRequest(url, method, options)
 	if err != nil {
+		ctx, span := v.startSpan(ctx, method, "")
 		if !v.logger.IsNoop() {
-			v.logger.Error(ctx, "failed to create request", map[string]interface{}{
-				"url":    url,
-				"method": method,
-				"error":  err.Error(),
-			})
+			v.logger.Error(ctx, "failed to create request", v.requestLogFields(ctx, nil, map[string]interface{}{
+				"url":         url,
+				"method":      method,
+				"duration_ms": time.Since(startTime).Milliseconds(),
+				"error":       err.Error(),
+			}))
 		}
 		v.recordMetricsWithFallback(ctx, method, url, nil, nil, time.Since(startTime), err)
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		err = fmt.Errorf("failed to create request: %w", err)
+		endSpan(span, nil, err)
+		return nil, err
 	}
 
+	ctx, span := v.startSpan(ctx, method, request.Route())
+	res, err = v.doRequest(ctx, request, startTime, options)
+	endSpan(span, res, err)
+
+	return res, err
+}
+
+func (v *Vecto) doRequest(ctx context.Context, request *Request, startTime time.Time, options *RequestOptions) (res *Response, err error) {
 	if !v.logger.IsNoop() {
-		v.logger.Debug(ctx, "request created", map[string]interface{}{
-			"url":    request.FullUrl(),
-			"method": request.Method(),
-		})
+		v.logger.Debug(ctx, "request created", v.requestLogFields(ctx, request, nil))
 	}
 
+	v.injectTraceContext(ctx, request)
+
+	ctx = withRequestStats(ctx)
+	ctx = withRoute(ctx, request.Route())
+
+	defer v.trackInFlight(ctx, request)()
+
 	if v.config.Adapter != nil {
 		result, adapterErr := v.config.Adapter(request)
 		v.recordMetrics(ctx, request, result, time.Since(startTime), adapterErr)
 		return result, adapterErr
 	}
 
-	request, err = v.interceptRequest(ctx, request)
+	interceptors := v.middleware.getCall()
+	if options != nil {
+		interceptors = append(interceptors, options.Middleware...)
+	}
+
+	state := &callState{request: request}
+	send := chainInterceptors(interceptors, func(ctx context.Context, req *Request) (*Response, error) {
+		return v.send(ctx, req, options, state, startTime)
+	})
+
+	res, err = send(ctx, request)
+	if err == nil && res == nil {
+		err = fmt.Errorf("interceptor returned no response")
+	}
 	if err != nil {
-		if !v.logger.IsNoop() {
-			v.logger.Error(ctx, "request middleware failed", map[string]interface{}{
-				"url":   request.FullUrl(),
-				"error": err.Error(),
-			})
+		return v.handleCallError(ctx, state, startTime, options, err)
+	}
+
+	request = state.request
+	if res.request == nil {
+		// The response was returned by an interceptor without sending the request.
+		res.request = request
+		res.success = v.config.ValidateStatus(res)
+	}
+
+	if res.exhaustedAttempts > 0 {
+		cause := fmt.Errorf("request failed after %d attempts: status code %d", res.exhaustedAttempts, res.StatusCode)
+		if fallbackRes, ok := v.requestHandler.executeFallback(ctx, request, v.getFallback(options), cause); ok {
+			res = fallbackRes
 		}
-		v.recordMetrics(ctx, request, nil, time.Since(startTime), err)
-		return nil, fmt.Errorf("request middleware failed: %w", err)
 	}
 
+	v.completeCall(ctx, request, res, options, startTime, nil)
+
+	return res, nil
+}
+
+// completeCall finishes a call that produced res, whether sent or served by
+// the fallback: it writes the debug output, dispatches the completion event
+// and records the metrics. cause is the error a fallback replaced, if any.
+func (v *Vecto) completeCall(ctx context.Context, req *Request, res *Response, options *RequestOptions, startTime time.Time, cause error) {
+	duration := time.Since(startTime)
+
+	if v.debugEnabled(options) {
+		v.writeDebugOutput(ctx, req, res)
+	}
+
+	v.channelDispatcher.dispatch(ctx, res)
+
+	v.recordMetrics(ctx, req, res, duration, cause)
+}
+
+// callState carries what the end of the interceptor chain learned about a
+// call back to doRequest.
+type callState struct {
+	request *Request
+	breaker *CircuitBreaker
+	cbKey   string
+}
+
+// send is the end of the interceptor chain: it sends req through the
+// circuit breaker and retries.
+func (v *Vecto) send(ctx context.Context, req *Request, options *RequestOptions, state *callState, startTime time.Time) (res *Response, err error) {
+	state.request = req
 	retryConfig := v.getRetryConfig(options)
 
 	var breaker *CircuitBreaker
-	var cbKey string
 
 	if v.circuitBreakerMgr != nil {
-		cbKey = v.requestHandler.getOrSetCircuitBreakerKey(request)
-		breaker = v.circuitBreakerMgr.GetOrCreate(cbKey, nil)
+		state.cbKey = v.requestHandler.getOrSetCircuitBreakerKey(req)
+		breaker = v.circuitBreakerMgr.GetOrCreate(state.cbKey, nil)
+		state.breaker = breaker
+		requestStatsFromContext(ctx).setBreakerState(breaker.GetState())
 
 		res, err = breaker.Execute(ctx, func() (*Response, error) {
-			return v.requestHandler.executeRequest(ctx, request, retryConfig, breaker)
+			return v.requestHandler.executeRequest(ctx, req, retryConfig, breaker)
 		})
-
-		if err != nil {
-			if _, isCbError := err.(*CircuitBreakerError); isCbError {
-				return v.requestHandler.handleCircuitBreakerError(ctx, request, cbKey, breaker, startTime, err)
-			}
-			return v.requestHandler.handleRequestError(ctx, request, method, startTime, err)
-		}
 	} else {
-		res, err = v.requestHandler.executeRequest(ctx, request, retryConfig, nil)
-		if err != nil {
-			return v.requestHandler.handleRequestError(ctx, request, method, startTime, err)
-		}
+		res, err = v.requestHandler.executeRequest(ctx, req, retryConfig, nil)
 	}
 
-	duration := time.Since(startTime)
+	if err != nil {
+		return nil, err
+	}
 
 	if !v.logger.IsNoop() {
-		v.logger.Info(ctx, "request completed", map[string]interface{}{
-			"url":         request.FullUrl(),
-			"method":      request.Method(),
+		v.logger.Info(ctx, "request completed", v.requestLogFields(ctx, req, map[string]interface{}{
 			"status_code": res.StatusCode,
-		})
+			"attempt":     requestStatsFromContext(ctx).attemptCount(),
+			"duration_ms": time.Since(startTime).Milliseconds(),
+		}))
 	}
 
 	res.success = v.config.ValidateStatus(res)
 
-	if v.circuitBreakerMgr != nil {
-		if breaker != nil {
-			breaker.RecordResult(res, nil)
-		}
+	if breaker != nil {
+		breaker.RecordResult(res, nil)
 	}
 
-	resultRes, err := v.interceptResponse(ctx, res)
-	if err != nil {
+	return res, nil
+}
+
+// handleCallError reports the error of a call and applies the fallback.
+func (v *Vecto) handleCallError(ctx context.Context, state *callState, startTime time.Time, options *RequestOptions, err error) (*Response, error) {
+	request := state.request
+
+	var mwErr *middlewareError
+	if errors.As(err, &mwErr) {
+		duration := time.Since(startTime)
 		if !v.logger.IsNoop() {
-			v.logger.Error(ctx, "response middleware failed", map[string]interface{}{
-				"url":         request.FullUrl(),
-				"status_code": res.StatusCode,
-				"error":       err.Error(),
-			})
+			fields := map[string]interface{}{
+				"duration_ms": duration.Milliseconds(),
+				"error":       mwErr.err.Error(),
+			}
+			if mwErr.res != nil {
+				fields["status_code"] = mwErr.res.StatusCode
+			}
+			v.logger.Error(ctx, mwErr.stage+" middleware failed", v.requestLogFields(ctx, request, fields))
 		}
-		v.recordMetrics(ctx, request, res, duration, err)
-		return nil, fmt.Errorf("response middleware failed: %w", err)
+		v.recordMetrics(ctx, request, mwErr.res, duration, mwErr.err)
+		return nil, fmt.Errorf("%s middleware failed: %w", mwErr.stage, mwErr.err)
 	}
 
-	if v.config.DebugMode {
-		v.writeDebugOutput(request, resultRes)
+	var cbErr *CircuitBreakerError
+	if errors.As(err, &cbErr) && state.breaker != nil {
+		return v.requestHandler.handleCircuitBreakerError(ctx, request, state.cbKey, state.breaker, startTime, options, err)
 	}
 
-	v.channelDispatcher.dispatch(ctx, resultRes)
-
-	v.recordMetrics(ctx, request, resultRes, duration, nil)
-
-	return resultRes, nil
+	return v.requestHandler.handleRequestError(ctx, request, request.Method(), startTime, options, err)
 }
 
-func (v *Vecto) interceptRequest(ctx context.Context, req *Request) (resultReq *Request, err error) {
-	resultReq = req
-	for _, mw := range v.middleware.getRequest() {
-		resultReq, err = mw(ctx, resultReq)
-		if err != nil {
-			return req, err
-		}
-	}
+// Intercept adds an interceptor wrapping the whole logical call: the
+// request middleware added after it, the circuit breaker, every retry and
+// the response middleware. Interceptors run in the order they were added,
+// the first one being the outermost, then the RequestOptions.Middleware of
+// the call. The fallback runs outside the chain, when it returns an error.
+//
+// Example:
+//
+//	vecto.Intercept(func(ctx context.Context, req *Request, next Next) (*Response, error) {
+//	    if res, ok := cache.Get(req.FullUrl()); ok {
+//	        return res, nil
+//	    }
+//	    return next(ctx, req)
+//	})
+func (v *Vecto) Intercept(interceptor Interceptor) {
+	v.middleware.addCall(interceptor)
+}
 
-	return resultReq, nil
+// InterceptAttempt adds an interceptor wrapping each attempt sent through
+// the Client, inside the retries and the circuit breaker. Interceptors run
+// in the order they were added, the first one being the outermost.
+func (v *Vecto) InterceptAttempt(interceptor Interceptor) {
+	v.middleware.addAttempt(interceptor)
 }
 
-func (v *Vecto) interceptResponse(ctx context.Context, res *Response) (resultRes *Response, err error) {
-	if res == nil {
-		return res, nil
+// Use adds a named, positioned interceptor. The middleware added with
+// Intercept, InterceptAttempt, UseRequest and UseResponse are unnamed and
+// have a priority of 0. Use returns an error if the name is already
+// registered or the Before or After middleware is not installed in the
+// same scope.
+//
+// Example:
+//
+//	err := vecto.Use(auth, MiddlewareOptions{Name: "auth", Priority: 10})
+//	err = vecto.Use(RequestInterceptor(sign), MiddlewareOptions{Name: "sign", After: "auth"})
+func (v *Vecto) Use(interceptor Interceptor, opts MiddlewareOptions) error {
+	if interceptor == nil {
+		return fmt.Errorf("interceptor cannot be nil")
 	}
+	return v.middleware.add(interceptor, middlewareKindInterceptor, opts)
+}
 
-	resultRes = res
-	for _, mw := range v.middleware.getResponse() {
-		resultRes, err = mw(ctx, resultRes)
-		if err != nil {
-			return resultRes, err
-		}
-	}
+// RemoveMiddleware removes the named middleware and reports whether it was
+// installed. Middleware placed before or after it fall back to their
+// priority.
+func (v *Vecto) RemoveMiddleware(name string) bool {
+	return v.middleware.remove(name)
+}
 
-	return resultRes, nil
+// ListMiddleware returns the installed middleware in the order they run,
+// the call middleware first.
+func (v *Vecto) ListMiddleware() []MiddlewareInfo {
+	return v.middleware.list()
 }
 
 // UseRequest adds a middleware function that will be executed before each request is sent.
 // Middleware functions are executed in the order they were added.
 // If a middleware returns an error, the request chain is stopped and the error is returned.
+// It is added to the chain of Intercept as a RequestInterceptor.
 //
 // Example:
 //
@@ -263,6 +367,8 @@ func (v *Vecto) UseRequest(mw RequestMiddlewareFunc) {
 // UseResponse adds a middleware function that will be executed after each response is received.
 // Middleware functions are executed in the order they were added.
 // If a middleware returns an error, the response chain is stopped and the error is returned.
+// It is added to the front of the chain of Intercept as a ResponseInterceptor, so
+// response middleware see the response returned by every interceptor.
 //
 // Example:
 //
@@ -291,6 +397,7 @@ func (v *Vecto) newRequest(urlStr string, method string, options *RequestOptions
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
 
+	urlTemplate := fullUrlStr
 	if reqOptions.PathParams != nil {
 		fullUrlStr = replacePathParams(fullUrlStr, reqOptions.PathParams)
 	}
@@ -342,6 +449,9 @@ func (v *Vecto) newRequest(urlStr string, method string, options *RequestOptions
 		return nil, err
 	}
 
+	req.route = v.resolveRoute(urlTemplate, reqOptions.PathParams, req)
+	req.redactor = v.redactor
+
 	return req, nil
 }
 
@@ -394,7 +504,7 @@ func shouldUseRetry(breaker *CircuitBreaker) bool {
 	return state != StateOpen
 }
 
-func (v *Vecto) writeDebugOutput(req *Request, res *Response) {
+func (v *Vecto) writeDebugOutput(ctx context.Context, req *Request, res *Response) {
 	if req == nil || res == nil {
 		return
 	}
@@ -404,29 +514,44 @@ func (v *Vecto) writeDebugOutput(req *Request, res *Response) {
 		trace = res.TraceInfo
 	}
 
-	writer := v.logger
-	if !writer.IsNoop() {
-		debugStr := formatDebugInfo(req, res, trace)
-		ctx := context.Background()
+	if config := v.config.Debug; config != nil && config.Writer != nil {
+		debugStr := formatDebugInfo(req, res, trace, config)
+		v.debugMu.Lock()
+		defer v.debugMu.Unlock()
+		_, _ = io.WriteString(config.Writer, debugStr)
+		return
+	}
+
+	if !v.logger.IsNoop() {
+		debugStr := formatDebugInfo(req, res, trace, v.config.Debug)
 		v.logger.Debug(ctx, debugStr, nil)
 	}
 }
 
-func formatDebugInfo(req *Request, res *Response, trace *TraceInfo) string {
+func formatDebugInfo(req *Request, res *Response, trace *TraceInfo, config *DebugConfig) string {
 	var b strings.Builder
+	rd := req.redaction()
 
 	b.WriteString("\n=== DEBUG INFO ===\n")
-	b.WriteString(fmt.Sprintf("Request: %s %s\n", req.Method(), req.FullUrl()))
+	b.WriteString(fmt.Sprintf("Request: %s %s\n", req.Method(), rd.url(req.FullUrl())))
 
-	if headers := req.Headers(); len(headers) > 0 {
+	headers := req.Headers()
+	if len(headers) > 0 {
 		b.WriteString("\nRequest Headers:\n")
-		for key, value := range headers {
-			b.WriteString(fmt.Sprintf("  %s: %s\n", key, value))
+		for _, key := range sortedKeys(headers) {
+			b.WriteString(fmt.Sprintf("  %s: %s\n", key, rd.header(key, headers[key])))
 		}
 	}
 
 	if req.Data() != nil {
-		b.WriteString(fmt.Sprintf("\nRequest Body:\n  %v\n", req.Data()))
+		var contentType string
+		for key, value := range headers {
+			if strings.EqualFold(key, "Content-Type") {
+				contentType = value
+			}
+		}
+		body := formatDebugBody(req.encodedBody(), contentType, rd, config)
+		b.WriteString(fmt.Sprintf("\nRequest Body:\n%s\n", body))
 	}
 
 	if res != nil {
@@ -434,19 +559,16 @@ func formatDebugInfo(req *Request, res *Response, trace *TraceInfo) string {
 
 		if headers := res.Headers(); len(headers) > 0 {
 			b.WriteString("\nResponse Headers:\n")
-			for key, values := range headers {
-				for _, value := range values {
-					b.WriteString(fmt.Sprintf("  %s: %s\n", key, value))
+			for _, key := range sortedKeys(headers) {
+				for _, value := range headers[key] {
+					b.WriteString(fmt.Sprintf("  %s: %s\n", key, rd.header(key, value)))
 				}
 			}
 		}
 
 		if len(res.Data) > 0 {
-			bodyPreview := string(res.Data)
-			if len(bodyPreview) > 500 {
-				bodyPreview = bodyPreview[:500] + "... (truncated)"
-			}
-			b.WriteString(fmt.Sprintf("\nResponse Body:\n%s\n", bodyPreview))
+			body := formatDebugBody(res.Data, res.Header("Content-Type"), rd, config)
+			b.WriteString(fmt.Sprintf("\nResponse Body:\n%s\n", body))
 		}
 	}
 
@@ -269,6 +269,18 @@ func TestConfigurableLimits(t *testing.T) {
 	assert.Equal(t, customMaxSize, vecto.config.MaxResponseBodySize)
 }
 
+func TestMergeConfigFlags(t *testing.T) {
+	vecto, err := New(Config{EnableTrace: true, DebugMode: true})
+	assert.NoError(t, err)
+	assert.True(t, vecto.config.EnableTrace)
+	assert.True(t, vecto.config.DebugMode)
+
+	vecto, err = New(Config{})
+	assert.NoError(t, err)
+	assert.False(t, vecto.config.EnableTrace)
+	assert.False(t, vecto.config.DebugMode)
+}
+
 func TestResponseErrorUnwrap(t *testing.T) {
 	t.Run("without underlying error", func(t *testing.T) {
 		err := &ResponseError{
@@ -390,7 +402,7 @@ func TestWriteDebugOutput(t *testing.T) {
 			success:    true,
 		}
 
-		vecto.writeDebugOutput(req, res)
+		vecto.writeDebugOutput(context.Background(), req, res)
 
 		assert.True(t, len(logger.debugCalls) > 0)
 		if len(logger.debugCalls) > 0 {
@@ -423,7 +435,7 @@ func TestWriteDebugOutput(t *testing.T) {
 			TraceInfo:  trace,
 		}
 
-		vecto.writeDebugOutput(req, res)
+		vecto.writeDebugOutput(context.Background(), req, res)
 
 		assert.True(t, len(logger.debugCalls) > 0)
 	})
@@ -436,7 +448,7 @@ func TestWriteDebugOutput(t *testing.T) {
 		})
 
 		res := &Response{StatusCode: 200}
-		vecto.writeDebugOutput(nil, res)
+		vecto.writeDebugOutput(context.Background(), nil, res)
 
 		assert.Equal(t, 0, len(logger.debugCalls))
 	})
@@ -449,7 +461,7 @@ func TestWriteDebugOutput(t *testing.T) {
 		})
 
 		req, _ := vecto.newRequest("/test", "GET", nil)
-		vecto.writeDebugOutput(req, nil)
+		vecto.writeDebugOutput(context.Background(), req, nil)
 
 		assert.Equal(t, 0, len(logger.debugCalls))
 	})
@@ -460,6 +472,6 @@ func TestWriteDebugOutput(t *testing.T) {
 		req, _ := vecto.newRequest("/test", "GET", nil)
 		res := &Response{StatusCode: 200}
 
-		vecto.writeDebugOutput(req, res)
+		vecto.writeDebugOutput(context.Background(), req, res)
 	})
 }
@@ -0,0 +1,447 @@
+// Package vectotest provides a mock transport for testing code that uses
+// vecto. Unlike Config.Adapter, the mock replaces only the network, so
+// middleware, retries, the circuit breaker, channels and metrics all run.
+//
+// Example:
+//
+//	func TestGetUser(t *testing.T) {
+//	    client, transport := vectotest.NewClient(t, vecto.Config{BaseURL: "https://api.test"})
+//	    transport.On(http.MethodGet, "/users/*").
+//	        Reply(http.StatusServiceUnavailable, "").
+//	        ReplyJSON(http.StatusOK, map[string]string{"name": "John"})
+//
+//	    ...
+//	}
+package vectotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caio-campos/vecto"
+)
+
+// TestingT is the subset of testing.TB used by the assertions.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Response is a stubbed response. When Err is set it is returned instead,
+// as a network error would be.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Err        error
+
+	// Delay is the latency simulated before the response is returned.
+	Delay time.Duration
+}
+
+// Request is a request received by the transport.
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Transport is an http.RoundTripper serving stubbed responses. Set it as
+// Config.HTTPTransport, or use NewClient. It is safe for concurrent use.
+type Transport struct {
+	mu        sync.Mutex
+	stubs     []*Stub
+	unmatched []Request
+}
+
+// NewTransport returns a Transport with no stubs.
+func NewTransport() *Transport {
+	return &Transport{}
+}
+
+// NewClient returns a client sending its requests through a new Transport,
+// whose expectations are asserted when the test ends.
+func NewClient(t testing.TB, config vecto.Config) (*vecto.Vecto, *Transport) {
+	t.Helper()
+
+	transport := NewTransport()
+	config.HTTPTransport = transport
+
+	client, err := vecto.New(config)
+	if err != nil {
+		t.Fatalf("vectotest: failed to create client: %v", err)
+	}
+
+	t.Cleanup(func() {
+		transport.AssertExpectations(t)
+	})
+
+	return client, transport
+}
+
+// On registers a stub for requests with the given method and URL pattern.
+// A pattern starting with "/" matches the URL path, otherwise the whole URL
+// without its query, unless the pattern has a query itself. A "*" matches
+// any sequence of characters. An empty method matches any method.
+//
+// When several stubs match a request, the first one registered that has
+// calls left is used.
+func (m *Transport) On(method, pattern string) *Stub {
+	stub := &Stub{method: method, pattern: pattern, times: -1}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stubs = append(m.stubs, stub)
+
+	return stub
+}
+
+// RoundTrip implements http.RoundTripper.
+func (m *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	received, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, ok := m.dispatch(req, received)
+	if !ok {
+		return nil, fmt.Errorf("vectotest: no stub matches %s %s", req.Method, received.URL)
+	}
+
+	if delay := response.Delay; delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+
+	if response.Err != nil {
+		return nil, response.Err
+	}
+
+	header := response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	status := response.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(response.Body)),
+		ContentLength: int64(len(response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// dispatch records received on the stub matching req and returns its
+// response, or records it as unmatched.
+func (m *Transport) dispatch(req *http.Request, received Request) (Response, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var exhausted *Stub
+	for _, stub := range m.stubs {
+		if !stub.matches(req) {
+			continue
+		}
+		if !stub.exhausted() {
+			return stub.call(received), true
+		}
+		if exhausted == nil {
+			exhausted = stub
+		}
+	}
+
+	// An exhausted stub still answers, so the extra call fails its
+	// expectation instead of the request.
+	if exhausted != nil {
+		return exhausted.call(received), true
+	}
+
+	m.unmatched = append(m.unmatched, received)
+	return Response{}, false
+}
+
+// Unmatched returns the requests that matched no stub.
+func (m *Transport) Unmatched() []Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Request(nil), m.unmatched...)
+}
+
+// AssertExpectations asserts that every request matched a stub and that
+// every stub was called as expected: the number of times set with Times,
+// or at least once.
+func (m *Transport) AssertExpectations(t TestingT) bool {
+	t.Helper()
+
+	m.mu.Lock()
+	stubs := append([]*Stub(nil), m.stubs...)
+	unmatched := append([]Request(nil), m.unmatched...)
+	m.mu.Unlock()
+
+	ok := true
+	for _, req := range unmatched {
+		t.Errorf("vectotest: unexpected request %s %s", req.Method, req.URL)
+		ok = false
+	}
+
+	for _, stub := range stubs {
+		stub.mu.Lock()
+		times, calls := stub.times, len(stub.requests)
+		stub.mu.Unlock()
+
+		switch {
+		case times >= 0 && calls != times:
+			t.Errorf("vectotest: %s expected %d calls, got %d", stub, times, calls)
+			ok = false
+		case times < 0 && calls == 0:
+			t.Errorf("vectotest: %s was never called", stub)
+			ok = false
+		}
+	}
+
+	return ok
+}
+
+// Stub answers the requests matching a method and URL pattern.
+type Stub struct {
+	method  string
+	pattern string
+	times   int
+	delay   time.Duration
+
+	mu        sync.Mutex
+	responses []Response
+	requests  []Request
+}
+
+// Respond adds a response to the sequence. Responses are returned in the
+// order they were added, the last one being repeated for later calls.
+func (s *Stub) Respond(response Response) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, response)
+	return s
+}
+
+// Reply adds a response with the given status and body to the sequence.
+func (s *Stub) Reply(status int, body string) *Stub {
+	return s.Respond(Response{StatusCode: status, Body: []byte(body)})
+}
+
+// ReplyJSON adds a response with the given status and v encoded as JSON
+// to the sequence.
+func (s *Stub) ReplyJSON(status int, v any) *Stub {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("vectotest: failed to encode JSON reply: %v", err))
+	}
+
+	return s.Respond(Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       body,
+	})
+}
+
+// ReplyError adds a response failing with err to the sequence.
+func (s *Stub) ReplyError(err error) *Stub {
+	return s.Respond(Response{Err: err})
+}
+
+// Delay adds latency to every response of the stub.
+func (s *Stub) Delay(d time.Duration) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+	return s
+}
+
+// Times sets the number of calls the stub expects. Once reached, later
+// matching requests go to the next matching stub, if any.
+func (s *Stub) Times(n int) *Stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.times = n
+	return s
+}
+
+// Once is Times(1).
+func (s *Stub) Once() *Stub {
+	return s.Times(1)
+}
+
+// Calls returns the number of requests the stub received.
+func (s *Stub) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+// Requests returns the requests the stub received, in order.
+func (s *Stub) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// AssertCalls asserts that the stub received n requests.
+func (s *Stub) AssertCalls(t TestingT, n int) bool {
+	t.Helper()
+
+	if calls := s.Calls(); calls != n {
+		t.Errorf("vectotest: %s expected %d calls, got %d", s, n, calls)
+		return false
+	}
+	return true
+}
+
+// AssertBody asserts that the last request received by the stub had the
+// given body. JSON bodies are compared by value.
+func (s *Stub) AssertBody(t TestingT, want string) bool {
+	t.Helper()
+
+	requests := s.Requests()
+	if len(requests) == 0 {
+		t.Errorf("vectotest: %s was never called", s)
+		return false
+	}
+
+	got := requests[len(requests)-1].Body
+	if !equalBodies(got, []byte(want)) {
+		t.Errorf("vectotest: %s received body %s, want %s", s, got, want)
+		return false
+	}
+	return true
+}
+
+func (s *Stub) String() string {
+	method := s.method
+	if method == "" {
+		method = "*"
+	}
+	return fmt.Sprintf("stub %s %s", method, s.pattern)
+}
+
+func (s *Stub) matches(req *http.Request) bool {
+	if s.method != "" && !strings.EqualFold(s.method, req.Method) {
+		return false
+	}
+
+	target := req.URL.Path
+	if !strings.HasPrefix(s.pattern, "/") {
+		u := *req.URL
+		u.RawQuery = ""
+		u.Fragment = ""
+		target = u.String()
+	}
+	if strings.Contains(s.pattern, "?") {
+		target += "?" + req.URL.RawQuery
+	}
+
+	return matchPattern(s.pattern, target)
+}
+
+func (s *Stub) exhausted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.times >= 0 && len(s.requests) >= s.times
+}
+
+// call records req and returns the response for it.
+func (s *Stub) call(req Request) Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, req)
+
+	response := Response{StatusCode: http.StatusOK}
+	if len(s.responses) > 0 {
+		i := len(s.requests) - 1
+		if i >= len(s.responses) {
+			i = len(s.responses) - 1
+		}
+		response = s.responses[i]
+	}
+
+	response.Delay += s.delay
+	return response
+}
+
+func newRequest(req *http.Request) (Request, error) {
+	received := Request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return received, err
+		}
+		received.Body = body
+	}
+
+	return received, nil
+}
+
+// matchPattern reports whether s matches pattern, where "*" matches any
+// sequence of characters.
+func matchPattern(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+
+	return strings.HasSuffix(s, last)
+}
+
+func equalBodies(got, want []byte) bool {
+	if bytes.Equal(got, want) {
+		return true
+	}
+
+	var a, b any
+	if json.Unmarshal(got, &a) != nil || json.Unmarshal(want, &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,127 @@
+package vectotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/caio-campos/vecto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestTransportSequence(t *testing.T) {
+	client, transport := NewClient(t, vecto.Config{
+		BaseURL: "https://api.test",
+		Retry:   &vecto.RetryConfig{MaxAttempts: 3, WaitTime: time.Millisecond},
+	})
+
+	users := transport.On(http.MethodPost, "/users").
+		Reply(http.StatusServiceUnavailable, "").
+		ReplyError(errors.New("connection reset")).
+		ReplyJSON(http.StatusCreated, map[string]int{"id": 1}).
+		Times(3)
+
+	var middlewareCalls int
+	client.UseRequest(func(ctx context.Context, req *vecto.Request) (*vecto.Request, error) {
+		middlewareCalls++
+		return req, nil
+	})
+
+	res, err := client.Post(context.Background(), "/users", &vecto.RequestOptions{
+		Data: map[string]string{"name": "John"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.JSONEq(t, `{"id":1}`, string(res.Data))
+	assert.Equal(t, "application/json", res.Header("Content-Type"))
+	assert.Equal(t, 1, middlewareCalls)
+
+	users.AssertCalls(t, 3)
+	users.AssertBody(t, `{ "name": "John" }`)
+	assert.Equal(t, "https://api.test/users", users.Requests()[0].URL)
+}
+
+func TestTransportPatterns(t *testing.T) {
+	client, transport := NewClient(t, vecto.Config{BaseURL: "https://api.test"})
+
+	transport.On(http.MethodGet, "/users/*/orders").Reply(http.StatusOK, "orders")
+	transport.On(http.MethodGet, "/users/*").Reply(http.StatusOK, "user")
+	transport.On("", "https://api.test/search?q=*").Reply(http.StatusOK, "search")
+
+	for path, want := range map[string]string{
+		"/users/1/orders": "orders",
+		"/users/1?full=1": "user",
+		"/search?q=go":    "search",
+	} {
+		res, err := client.Get(context.Background(), path, nil)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(res.Data), path)
+	}
+}
+
+func TestTransportDelay(t *testing.T) {
+	client, transport := NewClient(t, vecto.Config{BaseURL: "https://api.test"})
+	transport.On(http.MethodGet, "/slow").Delay(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.Get(ctx, "/slow", nil)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestTransportExpectations(t *testing.T) {
+	transport := NewTransport()
+	client, err := vecto.New(vecto.Config{BaseURL: "https://api.test", HTTPTransport: transport})
+	require.NoError(t, err)
+
+	once := transport.On(http.MethodGet, "/once").Once()
+	transport.On(http.MethodGet, "/never")
+
+	for i := 0; i < 2; i++ {
+		_, err = client.Get(context.Background(), "/once", nil)
+		require.NoError(t, err)
+	}
+
+	_, err = client.Get(context.Background(), "/unknown", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no stub matches GET https://api.test/unknown")
+	require.Len(t, transport.Unmatched(), 1)
+
+	rt := &recordingT{}
+	assert.False(t, transport.AssertExpectations(rt))
+	assert.Equal(t, []string{
+		"vectotest: unexpected request GET https://api.test/unknown",
+		"vectotest: stub GET /once expected 1 calls, got 2",
+		"vectotest: stub GET /never was never called",
+	}, rt.errors)
+
+	rt = &recordingT{}
+	assert.False(t, once.AssertBody(rt, "x"))
+	assert.Len(t, rt.errors, 1)
+}
+
+func TestMatchPattern(t *testing.T) {
+	assert.True(t, matchPattern("/a", "/a"))
+	assert.False(t, matchPattern("/a", "/ab"))
+	assert.True(t, matchPattern("/a/*", "/a/b/c"))
+	assert.True(t, matchPattern("*/c", "/a/b/c"))
+	assert.True(t, matchPattern("/a/*/c/*", "/a/b/c/d"))
+	assert.False(t, matchPattern("/ab*b", "/ab"))
+}